@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// runCheck implements `hexagen check [dir]`.
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: hexagen check [flags] [dir]")
+		fmt.Fprintln(fs.Output(), "Verifies that a generated project still matches the hexagen layout.")
+		fs.PrintDefaults()
+	}
+	disableEmoji := disableEmojiFlag(fs)
+	fs.Parse(args)
+	setupStatus(*disableEmoji)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	root := "."
+	if fs.NArg() == 1 {
+		root = fs.Arg(0)
+	}
+
+	problems, err := checkTree(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%s %s drifted from the hexagen layout:\n", markFail, root)
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "  - %s\n", p)
+		}
+		os.Exit(1)
+	}
+	fmt.Printf("%s %s matches the hexagen layout\n", markOK, root)
+}
+
+// checkTree checks root, or every module under services/ for a monorepo
+// root without a go.mod of its own.
+func checkTree(root string) ([]string, error) {
+	if info, err := os.Stat(root); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "go.mod")); os.IsNotExist(err) {
+		modules, _ := filepath.Glob(filepath.Join(root, "services", "*", "go.mod"))
+		if len(modules) > 0 {
+			var problems []string
+			for _, mod := range modules {
+				dir := filepath.Dir(mod)
+				rel, _ := filepath.Rel(root, dir)
+				found, err := checkProject(dir)
+				if err != nil {
+					return nil, err
+				}
+				for _, p := range found {
+					problems = append(problems, filepath.ToSlash(rel)+": "+p)
+				}
+			}
+			return problems, nil
+		}
+	}
+	return checkProject(root)
+}
+
+// checkProject compares one module against what hexagen generates for the
+// layout and services it finds there.
+func checkProject(root string) ([]string, error) {
+	var problems []string
+
+	module, goModProblems := readGoMod(filepath.Join(root, "go.mod"))
+	problems = append(problems, goModProblems...)
+	if module == "" {
+		// Imports can't be checked without the module path.
+		return problems, nil
+	}
+
+	cfg, found, err := detectConfig(root, module)
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, found...)
+	if len(cfg.Services) == 0 {
+		problems = append(problems, fmt.Sprintf("no services under %s/", cfg.layoutPath("services")))
+	}
+
+	for _, dir := range cfg.projectDirs() {
+		if !isDir(filepath.Join(root, cfg.layoutPath(dir))) {
+			problems = append(problems, "missing directory "+cfg.layoutPath(dir))
+		}
+	}
+	for _, f := range cfg.templateFiles() {
+		if !isFile(filepath.Join(root, cfg.layoutPath(f.Output))) {
+			problems = append(problems, "missing file "+cfg.layoutPath(f.Output))
+		}
+	}
+
+	for file, want := range cfg.wiring() {
+		path := filepath.Join(root, cfg.layoutPath(file))
+		if !isFile(path) {
+			continue // already reported as missing
+		}
+		imports, err := fileImports(path)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", cfg.layoutPath(file), err))
+			continue
+		}
+		for _, imp := range want {
+			if !slices.Contains(imports, imp) {
+				problems = append(problems, fmt.Sprintf("%s doesn't import %s", cfg.layoutPath(file), imp))
+			}
+		}
+	}
+
+	slices.Sort(problems)
+	return problems, nil
+}
+
+// detectConfig rebuilds the options an existing module was generated with
+// from the files hexagen left in it: the layout, framework, logger, JSON
+// library, API version, worker, cursors, per-route timeouts and services. It returns the
+// problems found on the way, such as service directories that can't be
+// services. Resources aren't detected; every service gets the default one.
+func detectConfig(root, module string) (Config, []string, error) {
+	cfg := Config{ModuleName: module, Framework: "gin", Logger: "zap", JSONLib: "std", APIVersion: "v1"}
+	cfg.Internal, cfg.BasePath = detectLayout(root)
+	cfg.Worker = isFile(filepath.Join(root, "cmd", "worker", "main.go"))
+	if isFile(filepath.Join(root, cfg.layoutPath("commons/server/json.go"))) {
+		cfg.Framework = "stdlib"
+	}
+	if isFile(filepath.Join(root, cfg.layoutPath("commons/middleware/requestid.go"))) {
+		cfg.Logger = "slog"
+	}
+	cfg.Cursor = isFile(filepath.Join(root, cfg.layoutPath("commons/utils/cursor/cursor.go")))
+	cfg.TimeoutPerRoute = isFile(filepath.Join(root, cfg.layoutPath("commons/server/timeout.go")))
+	if imports, err := fileImports(filepath.Join(root, cfg.layoutPath("commons/utils/json/json.go"))); err == nil {
+		for name, lib := range jsonLibs {
+			if lib.Module != "" && slices.Contains(imports, lib.Module) {
+				cfg.JSONLib = name
+			}
+		}
+	}
+
+	var problems []string
+	servicesDir := filepath.Join(root, cfg.layoutPath("services"))
+	entries, err := os.ReadDir(servicesDir)
+	if err != nil && !os.IsNotExist(err) {
+		return Config{}, nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if !serviceNamePattern.MatchString(e.Name()) {
+			problems = append(problems, fmt.Sprintf("%s: service names must be Go identifiers", cfg.layoutPath("services/"+e.Name())))
+			continue
+		}
+		cfg.Services = append(cfg.Services, e.Name())
+	}
+	if len(cfg.Services) > 0 {
+		// The routers register the version they serve in registerV<n>.
+		router, err := os.ReadFile(filepath.Join(root, cfg.layoutPath("services/"+cfg.Services[0]+"/routes/router.go")))
+		if m := registerFunc.FindSubmatch(router); err == nil && m != nil {
+			cfg.APIVersion = "v" + string(m[1])
+		}
+	}
+	return cfg, problems, nil
+}
+
+// registerFunc matches the function a router registers its routes for one
+// API version with.
+var registerFunc = regexp.MustCompile(`(?m)^func registerV(\d+)\(`)
+
+// detectLayout finds the directory holding the generated commons/ and
+// services/ trees and splits its path into the internal/ prefix and the
+// base path. A project using neither has them at the root.
+func detectLayout(root string) (internal bool, base string) {
+	var found string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+		switch {
+		case d.Name() == "cmd" || d.Name() == "vendor" || strings.HasPrefix(d.Name(), "."):
+			if rel != "." {
+				return filepath.SkipDir
+			}
+		case strings.Count(rel, "/") > 4:
+			return filepath.SkipDir
+		}
+		if isDir(filepath.Join(path, "commons")) && isDir(filepath.Join(path, "services")) {
+			found = rel
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if found == "" || found == "." {
+		return isDir(filepath.Join(root, "internal", "services")), ""
+	}
+	if found == "internal" {
+		return true, ""
+	}
+	if rest, ok := strings.CutPrefix(found, "internal/"); ok {
+		return true, rest
+	}
+	return false, found
+}
+
+// wiring maps generated files to the packages they must import for the
+// application graph to be complete.
+func (c Config) wiring() map[string][]string {
+	data := c.templateData()
+	app := []string{data.Imports.Server, data.Imports.Config, data.Imports.Utils}
+	worker := []string{data.Imports.Config, data.Imports.Utils}
+	wiring := map[string][]string{}
+
+	for _, svc := range data.Services {
+		dir := "services/" + svc.Name + "/"
+		app = append(app, svc.RoutesImport, svc.InitImport)
+		worker = append(worker, svc.InitImport)
+		wiring[dir+"routes/router.go"] = []string{svc.InternalImport}
+		wiring[dir+"internal/service.go"] = []string{svc.DataImport}
+		wiring[dir+"service_init/module.go"] = []string{svc.DataImport, svc.InternalImport}
+	}
+
+	wiring["cmd/main.go"] = app
+	if c.Worker {
+		wiring["cmd/worker/main.go"] = worker
+	}
+	return wiring
+}
+
+// readGoMod returns the module path declared in a go.mod and the problems
+// found with the file.
+func readGoMod(path string) (string, []string) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", []string{"missing go.mod"}
+	}
+	if err != nil {
+		return "", []string{fmt.Sprintf("go.mod: %v", err)}
+	}
+	defer f.Close()
+
+	var module string
+	var hasGo bool
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "module":
+			module = fields[1]
+			if unquoted, err := strconv.Unquote(module); err == nil {
+				module = unquoted
+			}
+		case "go":
+			hasGo = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", []string{fmt.Sprintf("go.mod: %v", err)}
+	}
+
+	var problems []string
+	if !hasGo {
+		problems = append(problems, "go.mod: missing go directive")
+	}
+	switch {
+	case module == "":
+		problems = append(problems, "go.mod: missing module directive")
+	case !modulePattern.MatchString(module):
+		problems = append(problems, fmt.Sprintf("go.mod: invalid module path %q", module))
+		module = ""
+	}
+	return module, problems
+}
+
+// fileImports returns the import paths of a Go file.
+func fileImports(path string) ([]string, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+	imports := make([]string, 0, len(f.Imports))
+	for _, imp := range f.Imports {
+		p, _ := strconv.Unquote(imp.Path.Value)
+		imports = append(imports, p)
+	}
+	return imports, nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestImportPathsFollowLayout(t *testing.T) {
+	tests := []struct {
+		name     string
+		internal bool
+		basePath string
+		prefix   string
+	}{
+		{name: "default", prefix: "example.com/orders/"},
+		{name: "internal", internal: true, prefix: "example.com/orders/internal/"},
+		{name: "base path", basePath: "pkg/app", prefix: "example.com/orders/pkg/app/"},
+		{name: "internal and base path", internal: true, basePath: "pkg/app", prefix: "example.com/orders/internal/pkg/app/"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t.TempDir())
+			cfg.Internal = tt.internal
+			cfg.BasePath = tt.basePath
+			data := cfg.templateData()
+
+			imports := map[string]string{
+				"Server":     data.Imports.Server,
+				"Config":     data.Imports.Config,
+				"Env":        data.Imports.Env,
+				"Error":      data.Imports.Error,
+				"Utils":      data.Imports.Utils,
+				"Middleware": data.Imports.Middleware,
+				"Query":      data.Imports.Query,
+			}
+			want := map[string]string{
+				"Server":     "commons/server",
+				"Config":     "config/init",
+				"Env":        "config/env",
+				"Error":      "commons/error",
+				"Utils":      "commons/utils",
+				"Middleware": "commons/middleware",
+				"Query":      "commons/utils/query",
+			}
+			for field, got := range imports {
+				if got != tt.prefix+want[field] {
+					t.Errorf("Imports.%s = %q, want %q", field, got, tt.prefix+want[field])
+				}
+			}
+
+			svc := data.Services[0]
+			for got, rel := range map[string]string{
+				svc.DataImport:     "services/orders/data",
+				svc.InternalImport: "services/orders/internal",
+				svc.RoutesImport:   "services/orders/routes",
+				svc.InitImport:     "services/orders/service_init",
+			} {
+				if got != tt.prefix+rel {
+					t.Errorf("service import %q, want %q", got, tt.prefix+rel)
+				}
+			}
+
+			wiring := cfg.wiring()
+			routes := "services/orders/routes/router.go"
+			if got := wiring[routes]; !slices.Equal(got, []string{svc.InternalImport}) {
+				t.Errorf("wiring[%s] = %q, want [%s]", routes, got, svc.InternalImport)
+			}
+			app := wiring["cmd/main.go"]
+			for _, pkg := range []string{data.Imports.Server, data.Imports.Config, data.Imports.Utils, svc.RoutesImport, svc.InitImport} {
+				if !slices.Contains(app, pkg) {
+					t.Errorf("wiring[cmd/main.go] = %q, missing %q", app, pkg)
+				}
+			}
+		})
+	}
+}
+
+func TestGeneratedMainImportsWiring(t *testing.T) {
+	for _, internal := range []bool{false, true} {
+		for _, basePath := range []string{"", "app"} {
+			root := t.TempDir()
+			cfg := testConfig(root)
+			cfg.Internal = internal
+			cfg.BasePath = basePath
+			if err := generate(cfg); err != nil {
+				t.Fatalf("generate with -internal=%v -base-path=%q: %v", internal, basePath, err)
+			}
+
+			for file, pkgs := range cfg.wiring() {
+				b, err := os.ReadFile(filepath.Join(root, cfg.layoutPath(file)))
+				if err != nil {
+					t.Errorf("-internal=%v -base-path=%q: %v", internal, basePath, err)
+					continue
+				}
+				for _, pkg := range pkgs {
+					if !strings.Contains(string(b), strconv.Quote(pkg)) {
+						t.Errorf("-internal=%v -base-path=%q: %s doesn't import %s", internal, basePath, cfg.layoutPath(file), pkg)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Client is a downstream service the generated app calls, given to -client
+// as name=url.
+type Client struct {
+	// Name is the package of the client under commons/clients and the
+	// prefix of its variables, e.g. payments and PAYMENTS_URL.
+	Name string `yaml:"name"`
+	// URL is the base URL used when <NAME>_URL is unset.
+	URL string `yaml:"url"`
+}
+
+// clientNamePattern keeps client names valid package names whose upper-case
+// form is a valid variable prefix.
+var clientNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// parseClients splits a comma-separated -client list and validates each
+// client.
+func parseClients(list string) ([]Client, error) {
+	var clients []Client
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		name, u, ok := strings.Cut(s, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid client %q: want name=url, e.g. payments=http://payments:8080", s)
+		}
+		clients = append(clients, Client{Name: name, URL: u})
+	}
+	return clients, validateClients(clients)
+}
+
+// validateClients rejects invalid, reserved and duplicate names and URLs
+// that aren't absolute http or https URLs.
+func validateClients(clients []Client) error {
+	seen := map[string]bool{}
+	for _, cl := range clients {
+		if !clientNamePattern.MatchString(cl.Name) || token.IsKeyword(cl.Name) {
+			return fmt.Errorf("invalid client name %q: use lower-case letters and digits, starting with a letter", cl.Name)
+		}
+		if seen[cl.Name] {
+			return fmt.Errorf("duplicate client %q", cl.Name)
+		}
+		seen[cl.Name] = true
+		if u, err := url.Parse(cl.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("client %s: invalid URL %q (want http:// or https:// and a host)", cl.Name, cl.URL)
+		}
+	}
+	return nil
+}
+
+// ClientData describes one generated client.
+type ClientData struct {
+	Name string
+	// GoName is the exported form of Name, e.g. Payments.
+	GoName string
+	// Env is the prefix of the client's variables, e.g. PAYMENTS.
+	Env    string
+	URL    string
+	Import string
+}
+
+func (c Config) clientData(name string) ClientData {
+	for _, cl := range c.Clients {
+		if cl.Name == name {
+			return ClientData{
+				Name:   cl.Name,
+				GoName: fieldGoName(cl.Name),
+				Env:    strings.ToUpper(cl.Name),
+				URL:    cl.URL,
+				Import: c.importPath("commons/clients/" + cl.Name),
+			}
+		}
+	}
+	return ClientData{}
+}
+
+// clientFiles lists the shared HTTP client and one package per client.
+func (c Config) clientFiles() []templateFile {
+	if len(c.Clients) == 0 {
+		return nil
+	}
+	files := []templateFile{
+		{Output: "commons/utils/httpclient/client.go", Template: "templates/httpClient.go.tmpl"},
+		{Output: "commons/utils/httpclient/client_test.go", Template: "templates/httpClientTest.go.tmpl"},
+		{Output: "config/init/clientConfig.go", Template: "templates/clientConfig.go.tmpl"},
+	}
+	for _, cl := range c.Clients {
+		files = append(files, templateFile{Output: "commons/clients/" + cl.Name + "/client.go", Template: "templates/client.go.tmpl", Client: cl.Name})
+	}
+	return files
+}
+
+// clientEnvVars are the variables config.NewClientConfig reads for each
+// client.
+func clientEnvVars(cfg Config) []envVar {
+	var vars []envVar
+	for _, cl := range cfg.Clients {
+		env := strings.ToUpper(cl.Name)
+		vars = append(vars,
+			envVar{Key: env + "_URL", Value: cl.URL, Comment: "Base URL of the " + cl.Name + " service", Check: "isHTTPURL"},
+			envVar{Key: env + "_TIMEOUT", Value: "5s", Comment: "Time each request to " + cl.Name + " gets, reading the response included", Check: "isPositiveDuration"},
+			envVar{Key: env + "_MAX_RETRIES", Value: "2", Comment: "Retries of idempotent requests to " + cl.Name + " after connection errors, 429, 502, 503 and 504", Check: "isNonNegativeInt"},
+			envVar{Key: env + "_RETRY_BACKOFF", Value: "100ms", Comment: "Wait before the first retry, doubled for each one after it", Check: "isPositiveDuration"},
+			envVar{Key: env + "_RPS", Value: "", Comment: "Requests per second sent to " + cl.Name + " at most, retries included (empty for no limit)", Check: "isPositiveNumber"},
+		)
+	}
+	return vars
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// editors are tried in order when $EDITOR is unset.
+var editors = []string{"code", "goland"}
+
+// editorCommand returns the command opening a project: $EDITOR, which may
+// carry arguments ("code -n"), or the first of editors found in PATH. It
+// returns nil when there is none.
+func editorCommand() []string {
+	if args := strings.Fields(os.Getenv("EDITOR")); len(args) > 0 {
+		return args
+	}
+	for _, name := range editors {
+		if _, err := exec.LookPath(name); err == nil {
+			return []string{name}
+		}
+	}
+	return nil
+}
+
+// isTerminal reports whether f is attached to a terminal rather than a pipe
+// or file, i.e. whether a person is watching.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// openEditor opens the generated project for -open. It does nothing when
+// the output isn't a terminal, so scripts piping hexagen never get an editor
+// started, and only warns when no editor is found or it fails: the project
+// is generated either way. A terminal editor such as vim takes over the
+// terminal until it exits; GUI editors return at once.
+func openEditor(cfg Config) {
+	if !cfg.Open || !isTerminal(os.Stdout) {
+		return
+	}
+	args := editorCommand()
+	if args == nil {
+		fmt.Fprintf(os.Stderr, "%s Warning: -open: no editor found; set $EDITOR or install one of %s\n", markWarn, strings.Join(editors, ", "))
+		return
+	}
+	cmd := exec.Command(args[0], append(args[1:], cfg.Root)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s Warning: -open: %s: %v\n", markWarn, args[0], err)
+	}
+}
@@ -0,0 +1,467 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// feature describes an optional capability of the generated project. The
+// registry below is the single place listing them: `hexagen features` prints
+// it and refuses to run when it drifts from the flags actually defined or
+// the files actually generated.
+type feature struct {
+	Name    string
+	Flag    string
+	Summary string
+	// Files are the paths the feature adds, relative to the default layout.
+	Files []string
+	// Modules are the requires the feature adds to go.mod.
+	Modules []string
+}
+
+var features = []feature{
+	{
+		Name:    "internal layout",
+		Flag:    "internal",
+		Summary: "Nest commons, config and services under internal/",
+		Files:   []string{"internal/commons/", "internal/config/", "internal/services/"},
+	},
+	{
+		Name:    "base path",
+		Flag:    "base-path",
+		Summary: "Nest commons, config and services under a path such as app/ below the module, imports included",
+		Files:   []string{"<base>/commons/", "<base>/config/", "<base>/services/"},
+	},
+	{
+		Name:    "worker",
+		Flag:    "worker",
+		Summary: "Background worker entrypoint sharing the services' modules",
+		Files:   []string{"cmd/worker/main.go", "services/<name>/internal/worker.go", "services/<name>/service_init/worker.go"},
+	},
+	{
+		Name:    "framework",
+		Flag:    "framework",
+		Summary: "HTTP framework: gin (default) or stdlib net/http routing",
+		Files:   []string{"commons/server/json.go (stdlib)"},
+		Modules: []string{"github.com/gin-gonic/gin (gin)"},
+	},
+	{
+		Name:    "slog logger",
+		Flag:    "logger",
+		Summary: "log/slog instead of zap, with request-ID and request-logging middleware that redacts credentials",
+		Files:   []string{"commons/utils/reqctx/reqctx.go", "commons/utils/reqctx/reqctx_test.go", "commons/middleware/requestid.go", "commons/middleware/logging.go", "commons/middleware/redact.go", "commons/middleware/redact_test.go"},
+		Modules: []string{"github.com/google/uuid"},
+	},
+	{
+		Name:    "trace ID header",
+		Flag:    "trace-id-header",
+		Summary: "Header the slog request-ID middleware reads and echoes instead of X-Request-ID",
+	},
+	{
+		Name:    "json library",
+		Flag:    "json-lib",
+		Summary: "JSON library behind commons/utils/json and the response helpers: std (encoding/json, default), jsoniter or sonic; gin is switched over with build tags",
+		Files:   []string{"commons/utils/json/json.go"},
+		Modules: []string{"github.com/json-iterator/go (jsoniter)", "github.com/bytedance/sonic (sonic)"},
+	},
+	{
+		Name:    "database",
+		Flag:    "db",
+		Summary: "database/sql pool tuned from env, pinged on start and closed on stop, and a SQL repository per service on the migrated tables",
+		Files:   []string{"config/init/dbConfig.go", "commons/db/db.go", "commons/db/tx.go", "commons/db/tx_test.go", "services/<name>/data/sql.go", "services/<name>/data/sql_test.go", "migrations/000001_init.up.sql", "migrations/000001_init.down.sql"},
+		Modules: []string{"github.com/jackc/pgx/v5 (postgres)", "github.com/go-sql-driver/mysql (mysql)"},
+	},
+	{
+		Name:    "connect rpc",
+		Flag:    "rpc",
+		Summary: "Serve every service over Connect (connect) next to its HTTP routes, on HTTP/2 without TLS, with the proto files, buf config and make buf-generate",
+		Files:   []string{"proto/<service>/v1/<service>.proto", "services/<name>/rpc/handler.go", "commons/server/rpc.go", "buf.yaml", "buf.gen.yaml"},
+		Modules: []string{"connectrpc.com/connect", "golang.org/x/net"},
+	},
+	{
+		Name:    "static files",
+		Flag:    "static",
+		Summary: "Static files of a project directory served under -static-path (/static), from disk or embedded with -static-embed",
+		Files:   []string{"commons/server/static.go"},
+	},
+	{
+		Name:    "embedded static files",
+		Flag:    "static-embed",
+		Summary: "With -static, compile the files into the binary with embed.FS from a package in the directory's parent",
+		Files:   []string{"<parent>/static.go"},
+	},
+	{
+		Name:    "static mount path",
+		Flag:    "static-path",
+		Summary: "URL path the -static files are served under, /static by default",
+	},
+	{
+		Name:    "api version",
+		Flag:    "api-version",
+		Summary: "Version segment of the service routes (/api/v1), registered per version",
+	},
+	{
+		Name:    "rate limiting",
+		Flag:    "ratelimit",
+		Summary: "Per-client token bucket returning 429 with Retry-After, configured from env",
+		Files:   []string{"commons/middleware/ratelimit.go"},
+	},
+	{
+		Name:    "gzip",
+		Flag:    "gzip",
+		Summary: "gzip response compression above a size threshold, skipping compressed content",
+		Files:   []string{"commons/middleware/gzip.go"},
+	},
+	{
+		Name:    "cors",
+		Flag:    "cors",
+		Summary: "CORS middleware answering preflights, with origins, methods and headers from env; permissive outside production, * rejected in production",
+		Files:   []string{"commons/middleware/cors.go", "config/init/cors_test.go"},
+	},
+	{
+		Name:    "websocket",
+		Flag:    "ws",
+		Summary: "gorilla/websocket echo endpoint with pings, read and write deadlines, a message size limit and close on shutdown",
+		Files:   []string{"commons/server/websocket.go"},
+		Modules: []string{"github.com/gorilla/websocket"},
+	},
+	{
+		Name:    "timeout-per-route",
+		Flag:    "timeout-per-route",
+		Summary: "Request timeouts set per route at registration, short for the health routes and longer for the resource routes, answering 503 when exceeded",
+		Files:   []string{"commons/server/timeout.go"},
+	},
+	{
+		Name:    "metrics",
+		Flag:    "metrics",
+		Summary: "Prometheus request count and duration at /metrics, labeled by route pattern rather than raw path, method and status",
+		Files:   []string{"commons/server/metrics.go", "commons/server/metrics_test.go"},
+		Modules: []string{"github.com/prometheus/client_golang"},
+	},
+	{
+		Name:    "audit",
+		Flag:    "audit",
+		Summary: "Audit log of who made each mutating request and its outcome, kept apart from the request log and written to a pluggable sink",
+		Files:   []string{"commons/middleware/audit.go", "commons/middleware/audit_test.go", "commons/constants/context.go"},
+	},
+	{
+		Name:    "clients",
+		Flag:    "client",
+		Summary: "Typed clients for downstream services, built on a shared HTTP client with timeouts, rate limiting and retries of idempotent requests",
+		Files:   []string{"commons/utils/httpclient/", "commons/clients/<name>/client.go", "config/init/clientConfig.go"},
+	},
+	{
+		Name:    "openapi",
+		Flag:    "openapi",
+		Summary: "OpenAPI 3 description of the health, server and resource routes, built from the same route list as the router templates",
+		Files:   []string{"openapi.yaml"},
+	},
+	{
+		Name:    "clock",
+		Flag:    "clock",
+		Summary: "Clock interface with real and fake implementations, injected into the services",
+		Files:   []string{"commons/utils/clock/clock.go"},
+	},
+	{
+		Name:    "cursor pagination",
+		Flag:    "cursor",
+		Summary: "Opaque base64 cursors paging the list endpoints by ID with ?cursor= and ?limit=, and a Link header to the next page",
+		Files:   []string{"commons/utils/cursor/cursor.go", "commons/utils/cursor/cursor_test.go"},
+	},
+	{
+		Name:    "cache",
+		Flag:    "cache",
+		Summary: "Cache port with an in-memory TTL map or Redis behind it, read through by the services' Get and invalidated on writes",
+		Files:   []string{"config/init/cacheConfig.go", "commons/utils/cache/cache.go", "commons/utils/cache/cache_test.go", "commons/utils/cache/redis.go (redis)"},
+		Modules: []string{"github.com/redis/go-redis/v9 (redis)"},
+	},
+	{
+		Name:    "feature flags",
+		Flag:    "featureflags",
+		Summary: "Boolean FEATURE_<NAME>_ENABLED toggles read from env, with an example endpoint gated behind one",
+		Files:   []string{"config/init/featureFlags.go", "commons/server/preview.go", "commons/server/preview_test.go"},
+	},
+	{
+		Name:    "per-service main",
+		Flag:    "per-service-main",
+		Summary: "One entrypoint per service wiring only that service, built by make build-<name>, next to the shared one",
+		Files:   []string{"cmd/<name>/main.go"},
+	},
+	{
+		Name:    "seed data",
+		Flag:    "seed-data",
+		Summary: "cmd/seed and make seed inserting example rows through the repositories (a no-op for in-memory ones)",
+		Files:   []string{"cmd/seed/main.go"},
+	},
+	{
+		Name:    "mocks",
+		Flag:    "mocks",
+		Summary: "Hand-written MockRepository recording its calls, and service unit tests run by make test against it",
+		Files:   []string{"services/<name>/data/mock.go", "services/<name>/internal/service_test.go"},
+	},
+	{
+		Name:    "healthcheck",
+		Flag:    "healthcheck",
+		Summary: "cmd/healthcheck probing GET /healthz on PORT and exiting 0 or 1, run by the Dockerfile's HEALTHCHECK",
+		Files:   []string{"cmd/healthcheck/main.go"},
+	},
+	{
+		Name:    "license header",
+		Flag:    "license-header",
+		Summary: "Copyright and SPDX-License-Identifier comment from -license and -author at the top of every generated .go file",
+	},
+	{
+		Name:    "port from env only",
+		Flag:    "port-from-env-only",
+		Summary: "Keep PORT out of the Makefile; make run takes it from .env",
+	},
+	{
+		Name:    "skip makefile",
+		Flag:    "skip-makefile",
+		Summary: "No Makefile; the next steps and the monorepo CI run go commands directly",
+	},
+	{
+		Name:    "docker",
+		Flag:    "docker",
+		Summary: "Multi-stage Dockerfile with a distroless runtime image",
+		Files:   []string{"Dockerfile", ".dockerignore"},
+	},
+	{
+		Name:    "helm",
+		Flag:    "helm",
+		Summary: "Helm chart with a Deployment and a Service",
+		Files:   []string{"charts/<name>/Chart.yaml", "charts/<name>/values.yaml", "charts/<name>/templates/"},
+	},
+	{
+		Name:    "ingress",
+		Flag:    "ingress-host",
+		Summary: "Ingress in the Helm chart routing the given host to the service",
+		Files:   []string{"charts/<name>/templates/ingress.yaml"},
+	},
+	{
+		Name:    "registry",
+		Flag:    "registry",
+		Summary: "Registry prefixing the image name in make docker-build, make docker-push and the Helm values",
+	},
+	{
+		Name:    "procfile",
+		Flag:    "procfile",
+		Summary: "Procfile running the built binary; the platform's $PORT reaches the config",
+		Files:   []string{"Procfile"},
+	},
+	{
+		Name:    "changelog",
+		Flag:    "changelog",
+		Summary: "Keep a Changelog CHANGELOG.md and a VERSION file stamped into the binary",
+		Files:   []string{"CHANGELOG.md", "VERSION"},
+	},
+	{
+		Name:    "build info",
+		Flag:    "buildinfo",
+		Summary: "GET /version with the version, commit and build time from ldflags, falling back to the embedded build info",
+		Files:   []string{"commons/server/version.go"},
+	},
+	{
+		Name:    "tool versions",
+		Flag:    "toolversions",
+		Summary: "asdf .tool-versions pinning Go to the -since-go or local toolchain version",
+		Files:   []string{".tool-versions"},
+	},
+	{
+		Name:    "offline",
+		Flag:    "offline",
+		Summary: "Skip network operations and pin requires in go.mod",
+	},
+	{
+		Name:    "module check",
+		Flag:    "module-check",
+		Summary: "Advisory pre-generation check of the module path: misspelt or unresolvable host, implausible or unknown repository owner; skipped with -offline",
+	},
+	{
+		Name:    "deps mode",
+		Flag:    "deps-mode",
+		Summary: "full pins every enabled feature's require in go.mod, so builds resolve the same versions (reproducible, but a longer go.mod to bump by hand); minimal leaves them to go mod tidy, which resolves the latest versions (lean, but they drift between runs)",
+	},
+	{
+		Name:    "toolchain",
+		Flag:    "toolchain",
+		Summary: "go.mod toolchain directive pinning the exact Go release every machine builds with",
+	},
+	{
+		Name:    "replace",
+		Flag:    "replace",
+		Summary: "go.mod replace directives, e.g. building against a local checkout of a dependency while developing both",
+	},
+	{
+		Name:    "vendor",
+		Flag:    "vendor",
+		Summary: "Vendor dependencies and build with -mod=vendor",
+		Files:   []string{"vendor/"},
+	},
+	{
+		Name:    "services",
+		Flag:    "services",
+		Summary: "One hexagonal tree per service, mounted under /api/<version>/<name>",
+		Files:   []string{"services/<name>/"},
+	},
+	{
+		Name:    "monorepo",
+		Flag:    "monorepo",
+		Summary: "One module per service with shared Makefile, lint config and CI",
+		Files:   []string{"services/<name>/go.mod", "Makefile", ".golangci.yml", ".github/workflows/ci.yml"},
+	},
+	{
+		Name:    "environments",
+		Flag:    "envs",
+		Summary: "Per-environment .env files selected by APP_ENV",
+		Files:   []string{".env.<env>", "config/env/loader.go", "config/env/loader_test.go"},
+	},
+	{
+		Name:    "dotenv",
+		Flag:    "dotenv",
+		Summary: "Load .env at startup with godotenv, skipped when APP_ENV is production",
+		Files:   []string{"config/env/loader.go", "config/env/loader_test.go"},
+		Modules: []string{"github.com/joho/godotenv"},
+	},
+	{
+		Name:    "config reload",
+		Flag:    "reload",
+		Summary: "Re-read the .env files on SIGHUP and apply LOG_LEVEL without a restart, logging the other changes as ignored",
+		Files:   []string{"config/init/reload.go", "config/init/reload_test.go"},
+	},
+	{
+		Name:    "template overrides",
+		Flag:    "templates",
+		Summary: "Render user templates instead of the built-in ones with the same name",
+	},
+	{
+		Name:    "gitkeep",
+		Flag:    "g",
+		Summary: "Keep empty directories in git",
+		Files:   []string{"<dir>/.gitkeep"},
+	},
+	{
+		Name:    "gitkeep on empty directories only",
+		Flag:    "no-gitkeep-on-populated",
+		Summary: "With -g, skip .gitkeep in the directories files are generated into, so none sits next to real code",
+	},
+}
+
+// baseFlags configure generation itself rather than enabling a feature.
+var baseFlags = slices.Concat(coreFlags, outputFlags)
+
+// featureConfigs turn on, by flag, the features whose Files list Go files,
+// for checkFeatureFiles. A feature with variants selects the one generating
+// every file listed, qualified ones such as "(redis)" included.
+var featureConfigs = map[string]func(*Config){
+	"worker":            func(c *Config) { c.Worker = true },
+	"framework":         func(c *Config) { c.Framework = "stdlib" },
+	"logger":            func(c *Config) { c.Logger = "slog" },
+	"json-lib":          func(c *Config) { c.JSONLib = "jsoniter" },
+	"db":                func(c *Config) { c.DB = "postgres" },
+	"rpc":               func(c *Config) { c.RPC = "connect" },
+	"static":            func(c *Config) { c.Static = "web/public" },
+	"static-embed":      func(c *Config) { c.Static, c.StaticEmbed = "web/public", true },
+	"ratelimit":         func(c *Config) { c.RateLimit = true },
+	"gzip":              func(c *Config) { c.Gzip = true },
+	"cors":              func(c *Config) { c.CORS = true },
+	"ws":                func(c *Config) { c.WebSocket = true },
+	"timeout-per-route": func(c *Config) { c.TimeoutPerRoute = true },
+	"metrics":           func(c *Config) { c.Metrics = true },
+	"audit":             func(c *Config) { c.Audit = true },
+	"client":            func(c *Config) { c.Clients = []Client{{Name: "payments", URL: "http://payments"}} },
+	"clock":             func(c *Config) { c.Clock = true },
+	"cursor":            func(c *Config) { c.Cursor = true },
+	"cache":             func(c *Config) { c.Cache = "redis" },
+	"featureflags":      func(c *Config) { c.FeatureFlags = true },
+	"per-service-main":  func(c *Config) { c.PerServiceMain = true },
+	"seed-data":         func(c *Config) { c.SeedData = true },
+	"mocks":             func(c *Config) { c.Mocks = true },
+	"healthcheck":       func(c *Config) { c.Healthcheck = true },
+	"buildinfo":         func(c *Config) { c.BuildInfo = true },
+	"envs":              func(c *Config) { c.Envs = []string{"dev", "prod"} },
+	"dotenv":            func(c *Config) { c.Dotenv = true },
+	"reload":            func(c *Config) { c.Dotenv, c.Reload = true, true },
+}
+
+// placeholder matches the <name>-style path elements of registry Files.
+var placeholder = regexp.MustCompile(`<[^>]+>`)
+
+// checkFeatureFiles reports the Go files of the registry that generation
+// doesn't render with their feature on. Go files all come from templates,
+// so templateFiles is the reference; a placeholder matches any one path
+// element.
+func checkFeatureFiles() []string {
+	var problems []string
+	for _, f := range features {
+		var outputs []string
+		for _, file := range f.Files {
+			file, _, _ = strings.Cut(file, " (")
+			if !strings.HasSuffix(file, ".go") {
+				continue
+			}
+			enable, ok := featureConfigs[f.Flag]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("feature %q lists %s but has no entry in featureConfigs", f.Name, file))
+				continue
+			}
+			if outputs == nil {
+				cfg := Spec{Module: "example.com/registry", Services: []ServiceSpec{{Name: "orders"}}}.config()
+				enable(&cfg)
+				for _, tf := range cfg.templateFiles() {
+					outputs = append(outputs, tf.Output)
+				}
+			}
+			pattern := regexp.MustCompile("^" + placeholder.ReplaceAllString(regexp.QuoteMeta(file), "[^/]+") + "$")
+			if !slices.ContainsFunc(outputs, pattern.MatchString) {
+				problems = append(problems, fmt.Sprintf("feature %q lists %s, which -%s doesn't generate", f.Name, file, f.Flag))
+			}
+		}
+	}
+	return problems
+}
+
+// checkFeatureRegistry reports flags missing from the registry, registry
+// entries pointing at flags that do not exist and listed Go files that
+// aren't generated.
+func checkFeatureRegistry() error {
+	problems := checkFeatureFiles()
+	for _, f := range features {
+		if flag.Lookup(f.Flag) == nil {
+			problems = append(problems, fmt.Sprintf("feature %q refers to undefined flag -%s", f.Name, f.Flag))
+		}
+	}
+	flag.VisitAll(func(fl *flag.Flag) {
+		if slices.Contains(baseFlags, fl.Name) {
+			return
+		}
+		if !slices.ContainsFunc(features, func(f feature) bool { return f.Flag == fl.Name }) {
+			problems = append(problems, fmt.Sprintf("flag -%s is not registered as a feature", fl.Name))
+		}
+	})
+	if len(problems) > 0 {
+		return fmt.Errorf("feature registry out of date:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// printFeatures writes the feature registry in a human-readable form.
+func printFeatures(w io.Writer) error {
+	if err := checkFeatureRegistry(); err != nil {
+		return err
+	}
+	for _, f := range features {
+		fmt.Fprintf(w, "%s (-%s)\n  %s\n", f.Name, f.Flag, f.Summary)
+		if len(f.Files) > 0 {
+			fmt.Fprintf(w, "  files:   %s\n", strings.Join(f.Files, ", "))
+		}
+		if len(f.Modules) > 0 {
+			fmt.Fprintf(w, "  modules: %s\n", strings.Join(f.Modules, ", "))
+		}
+	}
+	return nil
+}
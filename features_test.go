@@ -0,0 +1,12 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFeatureFilesAreGenerated(t *testing.T) {
+	if problems := checkFeatureFiles(); len(problems) > 0 {
+		t.Errorf("feature registry out of date:\n  %s", strings.Join(problems, "\n  "))
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// moduleFromGit derives a module path from the git remote of dir: origin,
+// or the first remote when there is no origin.
+func moduleFromGit(dir string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("git not found in PATH")
+	}
+	out, err := exec.Command("git", "-C", dir, "remote").Output()
+	if err != nil {
+		return "", fmt.Errorf("%s is not inside a git repository", dir)
+	}
+	remotes := strings.Fields(string(out))
+	if len(remotes) == 0 {
+		return "", fmt.Errorf("the repository in %s has no remote", dir)
+	}
+	remote := remotes[0]
+	for _, r := range remotes {
+		if r == "origin" {
+			remote = r
+		}
+	}
+
+	out, err = exec.Command("git", "-C", dir, "remote", "get-url", remote).Output()
+	if err != nil {
+		return "", fmt.Errorf("git remote get-url %s: %w", remote, err)
+	}
+	return modulePathFromRemote(strings.TrimSpace(string(out)))
+}
+
+// modulePathFromRemote turns a remote URL into a module path. It accepts
+// scp-like SSH remotes (git@github.com:me/orders.git) and URLs (https://,
+// ssh://, git://), dropping the user, port and .git suffix.
+func modulePathFromRemote(remote string) (string, error) {
+	var host, path string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil || u.Host == "" {
+			return "", fmt.Errorf("can't derive a module path from git remote %q", remote)
+		}
+		host, path = u.Hostname(), u.Path
+	} else if at, rest, ok := strings.Cut(remote, ":"); ok && !strings.Contains(at, "/") {
+		// scp-like syntax: [user@]host:path
+		if _, h, ok := strings.Cut(at, "@"); ok {
+			at = h
+		}
+		host, path = at, rest
+	} else {
+		return "", fmt.Errorf("unrecognised git remote %q", remote)
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	module := strings.ToLower(host) + "/" + path
+	if host == "" || path == "" || !modulePattern.MatchString(module) {
+		return "", fmt.Errorf("can't derive a module path from git remote %q", remote)
+	}
+	return module, nil
+}
+
+// gitDir is the directory whose repository is asked for its remote: the
+// target, or its nearest existing parent when it doesn't exist yet.
+func gitDir(root string) string {
+	dir := root
+	for !isDir(dir) {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return dir
+}
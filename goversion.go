@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	goversion "go/version"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// goRequirement is the oldest Go release able to build what a feature
+// generates.
+type goRequirement struct {
+	Version string
+	Reason  string
+	Enabled func(Config) bool
+}
+
+// goDirective is the go line of generated go.mod files.
+const goDirective = "1.22.0"
+
+// goRequirements maps features to the Go release they need. The go.mod
+// directive is the floor for every project.
+var goRequirements = []goRequirement{
+	{"go1.22", "the generated go.mod (go " + goDirective + ")", func(Config) bool { return true }},
+	{"go1.22", "-framework stdlib (method and wildcard routing patterns)", func(c Config) bool { return c.Framework == "stdlib" }},
+	{"go1.21", "-logger slog (log/slog)", func(c Config) bool { return c.Logger == "slog" }},
+	{"go1.20", "-gzip (http.ResponseController)", func(c Config) bool { return c.Gzip }},
+}
+
+// minGoVersion returns the newest release required by the selected
+// features and every reason for it.
+func (c Config) minGoVersion() (string, []string) {
+	min := "go1.0"
+	var reasons []string
+	for _, req := range goRequirements {
+		if !req.Enabled(c) {
+			continue
+		}
+		switch goversion.Compare(req.Version, min) {
+		case 1:
+			min, reasons = req.Version, []string{req.Reason}
+		case 0:
+			reasons = append(reasons, req.Reason)
+		}
+	}
+	return min, reasons
+}
+
+// localGoVersion reports the version of the go command in PATH, e.g.
+// "go1.22.5".
+func localGoVersion() (string, error) {
+	if _, err := exec.LookPath("go"); err != nil {
+		return "", fmt.Errorf("go toolchain not found in PATH")
+	}
+	out, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env GOVERSION: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// checkGoVersion fails when have, a Go version such as "1.21" or "go1.21.3",
+// is older than the selected features require.
+func checkGoVersion(c Config, have string) error {
+	if !strings.HasPrefix(have, "go") {
+		have = "go" + have
+	}
+	if !goversion.IsValid(have) {
+		return fmt.Errorf("invalid Go version %q", strings.TrimPrefix(have, "go"))
+	}
+	min, reasons := c.minGoVersion()
+	if goversion.Compare(have, min) < 0 {
+		return fmt.Errorf("Go %s is too old: %s or newer is required by %s",
+			strings.TrimPrefix(have, "go"), strings.TrimPrefix(min, "go"), strings.Join(reasons, ", "))
+	}
+	return nil
+}
+
+// verifyGoVersion checks the selected features against target, or against
+// the local toolchain when target is empty. A missing toolchain only warns,
+// as generation itself doesn't need one, unless -strict is set.
+func verifyGoVersion(c Config, target string) error {
+	if target == "" {
+		v, err := localGoVersion()
+		if err != nil {
+			if c.Strict {
+				return fmt.Errorf("checking the Go version: %w (-strict)", err)
+			}
+			fmt.Fprintf(os.Stderr, "%s Warning: skipping the Go version check: %v\n", markWarn, err)
+			return nil
+		}
+		if !goversion.IsValid(v) {
+			// Development builds report e.g. "devel go1.23-abcdef".
+			return nil
+		}
+		target = v
+	}
+	return checkGoVersion(c, target)
+}
+
+// toolVersion is the Go release pinned in .tool-versions: target when given,
+// else the local toolchain's, else the go.mod directive. asdf installs full
+// release names, so 1.23 becomes 1.23.0.
+func toolVersion(target string) string {
+	v := target
+	if v == "" {
+		v, _ = localGoVersion()
+	}
+	if !strings.HasPrefix(v, "go") {
+		v = "go" + v
+	}
+	if !goversion.IsValid(v) {
+		return goDirective
+	}
+	if goversion.Lang(v) == v {
+		v += ".0"
+	}
+	return strings.TrimPrefix(v, "go")
+}
+
+// validateToolchain checks a -toolchain value: a full release name such as
+// go1.23.4 or go1.24rc1, not older than the go directive.
+func validateToolchain(v string) error {
+	if !strings.HasPrefix(v, "go") || !goversion.IsValid(v) || goversion.Lang(v) == v {
+		return fmt.Errorf("invalid toolchain %q (want a release such as go1.23.4)", v)
+	}
+	if goversion.Compare(v, "go"+goDirective) < 0 {
+		return fmt.Errorf("toolchain %s is older than the go %s directive", v, goDirective)
+	}
+	return nil
+}
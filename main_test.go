@@ -0,0 +1,252 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testConfig is the configuration of a minimal project with one service,
+// written to root.
+func testConfig(root string) Config {
+	spec := Spec{
+		Root:     root,
+		Module:   "example.com/orders",
+		Services: []ServiceSpec{{Name: "orders"}},
+	}
+	return spec.config()
+}
+
+func TestGenerateCleanForce(t *testing.T) {
+	tests := []struct {
+		name         string
+		existing     bool
+		clean, force bool
+		wantErr      string
+		wantKept     bool
+	}{
+		{name: "empty target"},
+		{name: "non-empty target", existing: true, wantErr: "is not empty", wantKept: true},
+		{name: "force", existing: true, force: true, wantKept: true},
+		{name: "clean", existing: true, clean: true},
+		{name: "clean and force", existing: true, clean: true, force: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			kept := filepath.Join(root, "notes.txt")
+			if tt.existing {
+				if err := os.WriteFile(kept, []byte("keep me\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cfg := testConfig(root)
+			cfg.Clean = tt.clean
+			cfg.Force = tt.force
+
+			err := generate(cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("generate() error = %v, want one containing %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+
+			if _, err := os.Stat(kept); (err == nil) != tt.wantKept {
+				t.Errorf("notes.txt kept = %v, want %v", err == nil, tt.wantKept)
+			}
+			if _, err := os.Stat(filepath.Join(root, "go.mod")); (err == nil) != (tt.wantErr == "") {
+				t.Errorf("go.mod written = %v, want %v", err == nil, tt.wantErr == "")
+			}
+		})
+	}
+}
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{name: "CRLF line endings", in: "a: 1\r\nb: 2\r\n", want: "a: 1\nb: 2\n"},
+		{name: "missing trailing newline", in: "a: 1\nb: 2", want: "a: 1\nb: 2\n"},
+		{name: "several trailing newlines", in: "a: 1\n\n\n", want: "a: 1\n"},
+		{name: "leading blank lines", in: "\n  \na: 1\n", want: "a: 1\n"},
+		{name: "runs of blank lines", in: "a: 1\n\n \r\n\nb: 2\n", want: "a: 1\n\nb: 2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(normalizeText([]byte(tt.in))); got != tt.want {
+				t.Errorf("normalizeText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatOutput(t *testing.T) {
+	tests := []struct {
+		name, path, in, want string
+		wantErr              bool
+	}{
+		{name: "Go with CRLF", path: "cmd/main.go", in: "package main\r\n\r\nfunc main()  {}\r\n", want: "package main\n\nfunc main() {}\n"},
+		{name: "Go without trailing newline", path: "cmd/main.go", in: "package main", want: "package main\n"},
+		{name: "Go failing gofmt", path: "cmd/main.go", in: "package main\n\nfunc main() {\n", wantErr: true},
+		{name: "YAML with CRLF", path: "docker-compose.yml", in: "a: 1\r\nb: 2", want: "a: 1\nb: 2\n"},
+		{name: "invalid YAML", path: "config.yaml", in: "a: [1\n", wantErr: true},
+		{name: "Helm template YAML isn't parsed", path: "deploy/chart/templates/service.yaml", in: "{{ if .x }}\r\na: [1", want: "{{ if .x }}\na: [1\n"},
+		{name: "other text", path: "Dockerfile", in: "FROM golang\r\n\r\n\r\nRUN true", want: "FROM golang\n\nRUN true\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := formatOutput(tt.path, []byte(tt.in))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("formatOutput(%q) = %q, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("formatOutput(%q) error = %v", tt.in, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("formatOutput(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// overrideTemplate writes a -templates override named after templatePath and
+// returns the configuration that uses it.
+func overrideTemplate(t *testing.T, templatePath, body string) Config {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, filepath.Base(templatePath)), []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := testConfig(t.TempDir())
+	cfg.TemplatesDir = dir
+	return cfg
+}
+
+func TestRenderBytesReportsGofmtErrors(t *testing.T) {
+	cfg := overrideTemplate(t, "templates/broken.go.tmpl", "package main\n\nfunc main() {{ \"{\" }}\n")
+
+	_, err := renderBytes("broken.go", "templates/broken.go.tmpl", cfg, TemplateData{})
+	if err == nil || !strings.Contains(err.Error(), "format broken.go rendered from template") {
+		t.Fatalf("renderBytes() error = %v, want a format error naming the output and template", err)
+	}
+}
+
+func TestTemplateDelims(t *testing.T) {
+	tests := []struct {
+		name, src           string
+		wantLeft, wantRight string
+		wantBody            string
+		wantErr             bool
+	}{
+		{name: "no header", src: "a: {{ .X }}\n", wantLeft: "{{", wantRight: "}}", wantBody: "a: {{ .X }}\n"},
+		{name: "header", src: "# hexagen:delims [[ ]]\na: [[ .X ]]\n", wantLeft: "[[", wantRight: "]]", wantBody: "\na: [[ .X ]]\n"},
+		{name: "Go comment header", src: "// hexagen:delims <% %> keeps {{ }}\npackage x\n", wantLeft: "<%", wantRight: "%>", wantBody: "\npackage x\n"},
+		{name: "same delimiters", src: "# hexagen:delims %% %%\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left, right, body, err := templateDelims([]byte(tt.src))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("templateDelims(%q) error = nil, want one", tt.src)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("templateDelims(%q) error = %v", tt.src, err)
+			}
+			if left != tt.wantLeft || right != tt.wantRight || string(body) != tt.wantBody {
+				t.Errorf("templateDelims(%q) = %q, %q, %q, want %q, %q, %q", tt.src, left, right, body, tt.wantLeft, tt.wantRight, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestRenderBytesKeepsLiteralBraces(t *testing.T) {
+	const src = "# hexagen:delims [[ ]]\n" +
+		"name: [[ .ChartName ]]\n" +
+		"image: \"{{ .Values.image }}:{{ .Chart.AppVersion }}\"\n" +
+		"labels: {{- include \"app.labels\" . | nindent 4 }}\n"
+	cfg := overrideTemplate(t, "templates/deployment.yaml.tmpl", src)
+
+	got, err := renderBytes("deploy/chart/templates/deployment.yaml", "templates/deployment.yaml.tmpl", cfg, TemplateData{ChartName: "orders"})
+	if err != nil {
+		t.Fatalf("renderBytes() error = %v", err)
+	}
+	const want = "name: orders\n" +
+		"image: \"{{ .Values.image }}:{{ .Chart.AppVersion }}\"\n" +
+		"labels: {{- include \"app.labels\" . | nindent 4 }}\n"
+	if string(got) != want {
+		t.Errorf("renderBytes() = %q, want %q", got, want)
+	}
+}
+
+func TestValidateDefaultModule(t *testing.T) {
+	tests := []struct {
+		module  string
+		wantErr string
+	}{
+		{module: "service.com/service"},
+		{module: "example.com/team/orders"},
+		{module: "", wantErr: "-default-module is empty"},
+		{module: "bad path!", wantErr: `invalid module path "bad path!"`},
+		{module: "example.com//orders", wantErr: "invalid module path"},
+	}
+	for _, tt := range tests {
+		err := validateDefaultModule(tt.module)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("validateDefaultModule(%q) = %v, want nil", tt.module, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("validateDefaultModule(%q) = %v, want an error containing %q", tt.module, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSQLDataFollowsDriverAndMigration(t *testing.T) {
+	cfg := Spec{Module: "example.com/shop", Services: []ServiceSpec{{Name: "orders"}, {Name: "returns"}}}.config()
+	cfg.DB = "postgres"
+	cfg.Clock = true
+	got := cfg.sqlData("orders")
+	want := SQLData{
+		Table:    "orders_items",
+		Select:   "SELECT id, name, updated_at FROM orders_items",
+		Get:      "SELECT id, name, updated_at FROM orders_items WHERE id = $1",
+		Insert:   "INSERT INTO orders_items (id, name, updated_at) VALUES ($1, $2, $3)",
+		Lock:     "SELECT id FROM orders_items WHERE id = $1 FOR UPDATE",
+		Update:   "UPDATE orders_items SET name = $1, updated_at = $2 WHERE id = $3",
+		Delete:   "DELETE FROM orders_items WHERE id = $1",
+		Numbered: true,
+	}
+	if got != want {
+		t.Errorf("postgres sqlData = %+v\nwant %+v", got, want)
+	}
+
+	cfg.DB = "mysql"
+	cfg.Clock = false
+	if got := cfg.sqlData("returns").Update; got != "UPDATE returns_items SET name = ? WHERE id = ?" {
+		t.Errorf("mysql Update = %q", got)
+	}
+}
+
+func TestPrepareRootKeepsGitDirectory(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prepareRoot(testConfig(root)); err != nil {
+		t.Fatalf("prepareRoot() error = %v, want a target holding only .git to count as empty", err)
+	}
+	if !isDir(filepath.Join(root, ".git")) {
+		t.Error(".git was removed")
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// codeHosts are the hosts module paths commonly start with. The ones in
+// repoHosts serve repositories at host/owner/repo and an account page at
+// host/owner.
+var (
+	codeHosts = []string{"github.com", "gitlab.com", "bitbucket.org", "codeberg.org", "gitea.com", "git.sr.ht", "gopkg.in", "golang.org", "go.uber.org"}
+	repoHosts = []string{"github.com", "gitlab.com", "bitbucket.org", "codeberg.org", "gitea.com"}
+)
+
+// repoOwnerPattern and repoNamePattern are the account and repository names
+// the repoHosts accept, loosely: letters, digits and dashes for accounts,
+// plus dots and underscores for repositories.
+var (
+	repoOwnerPattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?$`)
+	repoNamePattern  = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// moduleCheckTimeout bounds the whole -module-check.
+const moduleCheckTimeout = 5 * time.Second
+
+// checkModule looks for signs that module is mistyped and returns them as
+// warnings: a host close to a well-known one, a repository path that
+// doesn't fit its host, a host that doesn't resolve and an account the host
+// doesn't know. It is advisory: network failures other than a missing host
+// or account are ignored, and a module without a dot in its first element,
+// which can't be fetched anyway, isn't checked at all.
+func checkModule(ctx context.Context, module string) []string {
+	host, rest, _ := strings.Cut(module, "/")
+	if !strings.Contains(host, ".") {
+		return nil
+	}
+	var warnings []string
+	if !slices.Contains(codeHosts, host) {
+		if c := closest(host, codeHosts); c != "" {
+			warnings = append(warnings, fmt.Sprintf("module host %q looks like a typo of %q", host, c))
+		}
+	}
+	owner, repo, _ := strings.Cut(rest, "/")
+	repo, _, _ = strings.Cut(repo, "/")
+	plausible := false
+	if slices.Contains(repoHosts, host) {
+		switch {
+		case owner == "" || repo == "":
+			warnings = append(warnings, fmt.Sprintf("module %q has no repository: %s modules are named %s/<owner>/<repo>", module, host, host))
+		case !repoOwnerPattern.MatchString(owner):
+			warnings = append(warnings, fmt.Sprintf("%q isn't a valid %s account name", owner, host))
+		case !repoNamePattern.MatchString(repo):
+			warnings = append(warnings, fmt.Sprintf("%q isn't a valid %s repository name", repo, host))
+		default:
+			plausible = true
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, moduleCheckTimeout)
+	defer cancel()
+	if err := lookupHost(ctx, host); err != nil {
+		switch {
+		// A well-known host that doesn't resolve means a restricted
+		// network, such as one reaching only a module proxy.
+		case slices.Contains(codeHosts, host) || lookupHost(ctx, controlHost) != nil:
+			warnings = append(warnings, fmt.Sprintf("-module-check: %s can't be reached, so only the spelling of the module path was checked", host))
+		case isNotFound(err):
+			warnings = append(warnings, fmt.Sprintf("module host %q doesn't resolve; go get won't find the module there", host))
+		}
+		return warnings
+	}
+	if plausible && accountMissing(ctx, host, owner) {
+		warnings = append(warnings, fmt.Sprintf("%s has no account %q; check the owner in the module path", host, owner))
+	}
+	return warnings
+}
+
+// controlHost always resolves with a working network; a module host that
+// doesn't while it does is missing rather than unreachable.
+const controlHost = "proxy.golang.org"
+
+func lookupHost(ctx context.Context, host string) error {
+	_, err := net.DefaultResolver.LookupHost(ctx, host)
+	return err
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// accountMissing reports whether host answers 404 for the page of owner.
+// The repository itself often doesn't exist yet when a project is
+// generated, so only the account is looked up.
+func accountMissing(ctx context.Context, host, owner string) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, "https://"+host+"/"+owner, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusNotFound
+}
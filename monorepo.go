@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// moduleRoots returns the directories holding a generated go.mod.
+func (c Config) moduleRoots() []string {
+	if !c.Monorepo {
+		return []string{c.Root}
+	}
+	roots := make([]string, 0, len(c.Services))
+	for _, name := range c.Services {
+		roots = append(roots, filepath.Join(c.Root, "services", name))
+	}
+	return roots
+}
+
+// monorepoModule derives the config of the module generated for the i-th
+// service of a monorepo. Services get consecutive ports so they can run side
+// by side.
+func (c Config) monorepoModule(i int, name string) Config {
+	sub := c
+	sub.Root = filepath.Join(c.Root, "services", name)
+	sub.ModuleName = c.ModuleName + "/services/" + name
+	sub.Services = []string{name}
+	sub.Replaces = replacesUnder(c.Replaces, "services/"+name)
+	sub.Monorepo = false
+	// One .tool-versions at the root covers every module.
+	sub.ToolVersions = false
+	// The monorepo root already went through the -clean/-force policy.
+	sub.Clean = false
+	sub.Force = true
+	if port, err := strconv.Atoi(c.Port); err == nil {
+		sub.Port = strconv.Itoa(port + i)
+	}
+	return sub
+}
+
+// generateMonorepo writes one independent module per service under services/
+// plus the tooling shared by all of them.
+func generateMonorepo(cfg Config) error {
+	rootAbs, err := prepareRoot(cfg)
+	if err != nil {
+		return err
+	}
+
+	for i, name := range cfg.Services {
+		if err := generate(cfg.monorepoModule(i, name)); err != nil {
+			return fmt.Errorf("service %s: %w", name, err)
+		}
+	}
+
+	if !cfg.SkipMakefile {
+		if err := writeMonorepoMakefile(rootAbs, cfg); err != nil {
+			return err
+		}
+	}
+	if err := writeGolangci(rootAbs); err != nil {
+		return err
+	}
+	if cfg.ToolVersions {
+		if err := writeToolVersions(rootAbs, cfg); err != nil {
+			return err
+		}
+	}
+	return writeCIWorkflow(rootAbs, cfg)
+}
+
+func writeMonorepoMakefile(root string, cfg Config) error {
+	content := `SERVICES := ` + strings.Join(cfg.Services, " ") + `
+
+.PHONY: build test tidy lint
+
+build test:
+	@for s in $(SERVICES); do $(MAKE) -C services/$$s $@ || exit 1; done
+
+tidy:
+	@for s in $(SERVICES); do (cd services/$$s && go mod tidy) || exit 1; done
+
+lint:
+	@for s in $(SERVICES); do (cd services/$$s && golangci-lint run --config ../../.golangci.yml ./...) || exit 1; done
+`
+	return writeFile(filepath.Join(root, "Makefile"), []byte(content))
+}
+
+func writeGolangci(root string) error {
+	content := `version: "2"
+
+linters:
+  default: standard
+  enable:
+    - misspell
+    - unconvert
+
+formatters:
+  enable:
+    - gofmt
+`
+	return writeFile(filepath.Join(root, ".golangci.yml"), []byte(content))
+}
+
+// ciSteps are the test and build steps of the CI workflow: the Makefile
+// targets, or the go commands they run without one.
+func ciSteps(cfg Config) string {
+	if cfg.SkipMakefile {
+		env := ""
+		if goFlags := cfg.goFlags(); goFlags != "" {
+			env = `
+        env:
+          GOFLAGS: ` + goFlags
+		}
+		return `      - name: Test
+        working-directory: services/${{ matrix.service }}` + env + `
+        run: go test ` + cfg.modFlag() + `./...
+
+      - name: Build
+        working-directory: services/${{ matrix.service }}` + env + `
+        run: go build ` + cfg.modFlag() + `-trimpath ./...
+`
+	}
+	return `      - name: Test
+        run: make -C services/${{ matrix.service }} test
+
+      - name: Build
+        run: make -C services/${{ matrix.service }} build
+`
+}
+
+func writeCIWorkflow(root string, cfg Config) error {
+	content := `name: CI
+
+on:
+  push:
+    branches: [main]
+  pull_request:
+
+jobs:
+  service:
+    runs-on: ubuntu-latest
+    strategy:
+      fail-fast: false
+      matrix:
+        service: [` + strings.Join(cfg.Services, ", ") + `]
+    steps:
+      - uses: actions/checkout@v4
+
+      - uses: actions/setup-go@v5
+        with:
+          go-version-file: services/${{ matrix.service }}/go.mod
+
+      - name: Lint
+        uses: golangci/golangci-lint-action@v8
+        with:
+          working-directory: services/${{ matrix.service }}
+          args: --config ../../.golangci.yml
+
+` + ciSteps(cfg)
+	dir := filepath.Join(root, ".github", "workflows")
+	if err := makeDir(dir); err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, "ci.yml"), []byte(content))
+}
@@ -0,0 +1,172 @@
+package main
+
+import "strings"
+
+// Endpoint is one route of the generated API, as openapi.yaml documents it.
+// The list built by endpoints follows the routes the templates register, so
+// a route added to the router or server templates is added there too.
+type Endpoint struct {
+	// Method is lower-case, as OpenAPI keys operations; Path uses {id} for
+	// path parameters, like the stdlib mux.
+	Method string
+	Path   string
+	// OperationID is unique across the document, e.g. listOrders.
+	OperationID string
+	Summary     string
+	// Tag groups the endpoints of a service; server routes have none.
+	Tag string
+	// Kind is the resource operation (list, get, create, update or delete),
+	// or the server route (health, ping, healthz, version, preview, ws,
+	// metrics).
+	Kind string
+	// Schema names the component schema of the resource, "" for server
+	// routes.
+	Schema   string
+	Resource ResourceData
+}
+
+// APIPath is the set of endpoints sharing a path, in registration order.
+type APIPath struct {
+	Path      string
+	Endpoints []Endpoint
+}
+
+// APISchema is the component schema of one service's resource.
+type APISchema struct {
+	Name     string
+	Resource ResourceData
+}
+
+// apiSchemas names each service's resource schema after its model, prefixed
+// with the service when services share a model, as the migrations do with
+// table names.
+func (c Config) apiSchemas() []APISchema {
+	models := map[string]int{}
+	for _, name := range c.Services {
+		models[c.resource(name).data().Model]++
+	}
+	var schemas []APISchema
+	for _, name := range c.Services {
+		r := c.resource(name).data()
+		schema := r.Model
+		if models[schema] > 1 {
+			schema = fieldGoName(name) + schema
+		}
+		schemas = append(schemas, APISchema{Name: schema, Resource: r})
+	}
+	return schemas
+}
+
+// endpoints lists every route of the HTTP app: the server routes, then
+// each service's resource routes.
+func (c Config) endpoints() []Endpoint {
+	api := "/api/" + c.APIVersion
+	list := []Endpoint{
+		{Method: "get", Path: "/", OperationID: "health", Summary: "Report that the service is up", Kind: "health"},
+		{Method: "get", Path: api + "/ping", OperationID: "ping", Summary: "Answer a ping", Kind: "ping"},
+	}
+	if c.Healthcheck {
+		list = append(list, Endpoint{Method: "get", Path: "/healthz", OperationID: "healthz", Summary: "Liveness probe used by cmd/healthcheck", Kind: "healthz"})
+	}
+	if c.BuildInfo {
+		list = append(list, Endpoint{Method: "get", Path: "/version", OperationID: "version", Summary: "Report the build info", Kind: "version"})
+	}
+	if c.FeatureFlags {
+		list = append(list, Endpoint{Method: "get", Path: api + "/preview", OperationID: "preview", Summary: "Preview endpoint behind the preview feature flag", Kind: "preview"})
+	}
+	if c.WebSocket {
+		list = append(list, Endpoint{Method: "get", Path: api + "/ws/echo", OperationID: "wsEcho", Summary: "Open a WebSocket echoing every message", Kind: "ws"})
+	}
+	if c.Metrics {
+		list = append(list, Endpoint{Method: "get", Path: "/metrics", OperationID: "metrics", Summary: "Export the Prometheus metrics", Kind: "metrics"})
+	}
+
+	schemas := c.apiSchemas()
+	for i, name := range c.Services {
+		s := schemas[i]
+		r := s.Resource
+		base := c.routePrefix(name) + "/" + r.Path
+		// Services sharing a model also share operation names; prefix
+		// them like the schemas.
+		plural, single := r.ModelPlural, r.Model
+		if s.Name != r.Model {
+			plural, single = fieldGoName(name)+plural, fieldGoName(name)+single
+		}
+		add := func(on bool, method, path, kind, summary string) {
+			if !on {
+				return
+			}
+			id := kind + single
+			if kind == "list" {
+				id = kind + plural
+			}
+			list = append(list, Endpoint{
+				Method:      method,
+				Path:        path,
+				OperationID: id,
+				Summary:     summary,
+				Tag:         name,
+				Kind:        kind,
+				Schema:      s.Name,
+				Resource:    r,
+			})
+		}
+		add(r.List, "get", base, "list", "List "+r.Path)
+		one := article(r.Label) + " " + r.Label
+		add(r.Create, "post", base, "create", "Create "+one)
+		add(r.Get, "get", base+"/{id}", "get", "Get "+one)
+		add(r.Update, "put", base+"/{id}", "update", "Replace "+one)
+		add(r.Delete, "delete", base+"/{id}", "delete", "Delete "+one)
+	}
+	return list
+}
+
+// article is the indefinite article of word, going by its first letter.
+func article(word string) string {
+	if word != "" && strings.ContainsRune("aeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// apiPaths groups endpoints by path, keeping the order paths first appear.
+func (c Config) apiPaths() []APIPath {
+	var paths []APIPath
+	index := map[string]int{}
+	for _, e := range c.endpoints() {
+		i, ok := index[e.Path]
+		if !ok {
+			i = len(paths)
+			index[e.Path] = i
+			paths = append(paths, APIPath{Path: e.Path})
+		}
+		paths[i].Endpoints = append(paths[i].Endpoints, e)
+	}
+	return paths
+}
+
+// OpenAPIType is the OpenAPI type of the field.
+func (f FieldData) OpenAPIType() string {
+	switch f.Type {
+	case "int", "int64":
+		return "integer"
+	case "float64":
+		return "number"
+	case "bool":
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// OpenAPIFormat refines OpenAPIType for numbers, "" for other types.
+func (f FieldData) OpenAPIFormat() string {
+	switch f.Type {
+	case "int", "int64":
+		return "int64"
+	case "float64":
+		return "double"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// output is where generation puts files: the target directory, or an
+// archive with -output zip or tgz.
+type output interface {
+	mkdirAll(path string) error
+	writeFile(path string, b []byte, mode fs.FileMode) error
+	exists(path string) bool
+	// empty reports whether the directory at path holds no entries.
+	empty(path string) bool
+}
+
+// sink is the current destination; execute swaps in an archiveOutput.
+var sink output = diskOutput{}
+
+// writtenFiles records every file generation wrote, as absolute paths, for
+// the -summary-file report. With -idempotent, skippedFiles and addedDirs
+// record what was already there and which directories were created.
+var (
+	writtenFiles []string
+	skippedFiles []string
+	addedDirs    []string
+)
+
+// skipExisting is set by -idempotent: files already present are left alone.
+var skipExisting bool
+
+// writeFile writes a generated file and records it.
+func writeFile(path string, b []byte) error {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if skipExisting && sink.exists(path) {
+		skippedFiles = append(skippedFiles, path)
+		return nil
+	}
+	if err := sink.writeFile(path, b, 0644); err != nil {
+		return err
+	}
+	writtenFiles = append(writtenFiles, path)
+	return nil
+}
+
+// makeDir creates a generated directory and its parents.
+func makeDir(path string) error {
+	if skipExisting && !sink.exists(path) {
+		if abs, err := filepath.Abs(path); err == nil {
+			addedDirs = append(addedDirs, abs)
+		}
+	}
+	return sink.mkdirAll(path)
+}
+
+type diskOutput struct{}
+
+func (diskOutput) mkdirAll(path string) error {
+	return os.MkdirAll(path, 0755)
+}
+
+func (diskOutput) writeFile(path string, b []byte, mode fs.FileMode) error {
+	return os.WriteFile(path, b, mode)
+}
+
+func (diskOutput) exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (diskOutput) empty(path string) bool {
+	entries, err := os.ReadDir(path)
+	return err == nil && len(entries) == 0
+}
+
+// archiveFormats maps the -output archive formats to their file extension.
+var archiveFormats = map[string]string{"zip": ".zip", "tgz": ".tar.gz"}
+
+// archived reports whether c builds the project in memory as an archive
+// instead of writing it into Root.
+func (c Config) archived() bool {
+	_, ok := archiveFormats[c.Output]
+	return ok
+}
+
+// archivePath is where the archive is written: -archive, or <name> plus the
+// format's extension in the working directory.
+func (c Config) archivePath() string {
+	if c.Archive != "" {
+		return c.Archive
+	}
+	return c.chartName() + archiveFormats[c.Output]
+}
+
+// archiveEncoder writes entries in one archive format; archiveOutput does the
+// bookkeeping shared by every format.
+type archiveEncoder interface {
+	add(name string, b []byte, mode fs.FileMode, modTime time.Time) error
+	close() error
+}
+
+// archiveOutput builds the project in memory as an archive. Paths are stored
+// relative to root; directories get their own entries so empty ones survive.
+type archiveOutput struct {
+	root    string
+	buf     bytes.Buffer
+	enc     archiveEncoder
+	entries map[string]bool
+	modTime time.Time
+}
+
+func newArchiveOutput(root, format string) *archiveOutput {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	a := &archiveOutput{root: root, entries: map[string]bool{}, modTime: time.Now()}
+	switch format {
+	case "tgz":
+		gz := gzip.NewWriter(&a.buf)
+		a.enc = tgzEncoder{gz: gz, tw: tar.NewWriter(gz)}
+	default:
+		a.enc = zipEncoder{zip.NewWriter(&a.buf)}
+	}
+	return a
+}
+
+// name returns the archive name of path, and false for the root itself.
+func (a *archiveOutput) name(path string) (string, bool) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	rel, err := filepath.Rel(a.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+func (a *archiveOutput) mkdirAll(path string) error {
+	name, ok := a.name(path)
+	if !ok {
+		return nil
+	}
+	if parent := filepath.Dir(path); parent != path {
+		if err := a.mkdirAll(parent); err != nil {
+			return err
+		}
+	}
+	if a.entries[name+"/"] {
+		return nil
+	}
+	a.entries[name+"/"] = true
+	return a.enc.add(name+"/", nil, fs.ModeDir|0755, a.modTime)
+}
+
+func (a *archiveOutput) writeFile(path string, b []byte, mode fs.FileMode) error {
+	name, ok := a.name(path)
+	if !ok {
+		return &fs.PathError{Op: "write", Path: path, Err: fs.ErrInvalid}
+	}
+	if err := a.mkdirAll(filepath.Dir(path)); err != nil {
+		return err
+	}
+	if a.entries[name] {
+		// An archive can't replace an entry; generation writes each file once.
+		return &fs.PathError{Op: "write", Path: path, Err: fs.ErrExist}
+	}
+	a.entries[name] = true
+	return a.enc.add(name, b, mode, a.modTime)
+}
+
+func (a *archiveOutput) exists(path string) bool {
+	name, ok := a.name(path)
+	return ok && (a.entries[name] || a.entries[name+"/"])
+}
+
+func (a *archiveOutput) empty(path string) bool {
+	name, ok := a.name(path)
+	if !ok || !a.entries[name+"/"] {
+		return false
+	}
+	for entry := range a.entries {
+		if entry != name+"/" && strings.HasPrefix(entry, name+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// writeTo finishes the archive and copies it to w.
+func (a *archiveOutput) writeTo(w io.Writer) error {
+	if err := a.enc.close(); err != nil {
+		return err
+	}
+	_, err := w.Write(a.buf.Bytes())
+	return err
+}
+
+type zipEncoder struct {
+	zw *zip.Writer
+}
+
+func (e zipEncoder) add(name string, b []byte, mode fs.FileMode, modTime time.Time) error {
+	h := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modTime}
+	if mode.IsDir() {
+		h.Method = zip.Store
+	}
+	h.SetMode(mode)
+	w, err := e.zw.CreateHeader(h)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func (e zipEncoder) close() error {
+	return e.zw.Close()
+}
+
+// tgzEncoder writes a gzip-compressed tarball, as read by tar -xz.
+type tgzEncoder struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (e tgzEncoder) add(name string, b []byte, mode fs.FileMode, modTime time.Time) error {
+	h := &tar.Header{
+		Name: name,
+		Mode: int64(mode.Perm()),
+		Size: int64(len(b)),
+		// Whole seconds: tar would round up into the future otherwise.
+		ModTime:  modTime.Truncate(time.Second),
+		Typeflag: tar.TypeReg,
+	}
+	if mode.IsDir() {
+		h.Typeflag = tar.TypeDir
+		h.Size = 0
+	}
+	if err := e.tw.WriteHeader(h); err != nil {
+		return err
+	}
+	_, err := e.tw.Write(b)
+	return err
+}
+
+func (e tgzEncoder) close() error {
+	if err := e.tw.Close(); err != nil {
+		return err
+	}
+	return e.gz.Close()
+}
+
+// printConvergence reports what an -idempotent run added and what it found
+// already in place, relative to the project root.
+func printConvergence(cfg Config) {
+	root, _ := filepath.Abs(cfg.Root)
+	rel := func(paths []string) []string {
+		var out []string
+		for _, p := range paths {
+			if r, err := filepath.Rel(root, p); err == nil {
+				p = r
+			}
+			out = append(out, filepath.ToSlash(p))
+		}
+		slices.Sort(out)
+		return slices.Compact(out)
+	}
+
+	added := rel(writtenFiles)
+	for _, d := range rel(addedDirs) {
+		added = append(added, d+"/")
+	}
+	slices.Sort(added)
+	skipped := rel(skippedFiles)
+
+	fmt.Printf("\nAdded %d, skipped %d existing:\n", len(added), len(skipped))
+	for _, p := range added {
+		fmt.Printf("  + %s\n", p)
+	}
+	for _, p := range skipped {
+		fmt.Printf("  = %s\n", p)
+	}
+}
+
+// validateOutput checks the -output options and settles the ones archive
+// modes imply: nothing is installed, so go.mod pins its requires as offline.
+func validateOutput(cfg *Config) error {
+	switch cfg.Output {
+	case "", "dir":
+		cfg.Output = "dir"
+		if cfg.Archive != "" {
+			return fmt.Errorf("-archive is only used with -output zip or tgz")
+		}
+		return nil
+	case "zip", "tgz":
+	default:
+		return fmt.Errorf("unknown output %q (valid: %s)%s", cfg.Output, strings.Join(outputModes, ", "), suggestion(cfg.Output, outputModes))
+	}
+	if cfg.Vendor {
+		return fmt.Errorf("-vendor needs the project on disk; it can't be combined with -output %s", cfg.Output)
+	}
+	if cfg.Clean || cfg.Force || cfg.Idempotent || cfg.Open {
+		return fmt.Errorf("-clean, -force, -idempotent and -open apply to the target directory, which -output %s never writes", cfg.Output)
+	}
+	cfg.Offline = true
+	if cfg.Archive == "-" {
+		// Keep stdout for the archive from here on; messages go to stderr.
+		archiveStdout = os.Stdout
+		os.Stdout = os.Stderr
+	}
+	return nil
+}
+
+// outputModes are the valid -output values.
+var outputModes = []string{"dir", "zip", "tgz"}
+
+// archiveStdout is the real stdout while -archive - redirects messages.
+var archiveStdout *os.File
+
+// executeArchive generates the project into an archive, written to stdout
+// with -archive -.
+func executeArchive(cfg Config) {
+	path := cfg.archivePath()
+	dest := archiveStdout
+
+	a := newArchiveOutput(cfg.Root, cfg.Output)
+	sink = a
+	run := generate
+	if cfg.Monorepo {
+		run = generateMonorepo
+	}
+	if err := run(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.SummaryFile != "" {
+		if err := writeSummary(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: writing summary: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if cfg.PrintTree {
+		// os.Stdout is stderr already when the archive goes to stdout.
+		printTree(os.Stdout, cfg)
+	}
+
+	var f *os.File
+	if path != "-" {
+		var err error
+		if f, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		dest = f
+	}
+	err := a.writeTo(dest)
+	if f != nil {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: writing %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	if path == "-" {
+		fmt.Fprintln(os.Stderr, markOK, "Project archive written to stdout")
+		return
+	}
+	name := cfg.chartName()
+	fmt.Printf("%s Project archive written to %s\n", markOK, path)
+	fmt.Println("\nNext steps:")
+	if cfg.Output == "tgz" {
+		fmt.Printf("  mkdir %s && tar -xzf %s -C %s && cd %s\n", name, path, name, name)
+	} else {
+		fmt.Printf("  unzip %s -d %s && cd %s\n", path, name, name)
+	}
+	fmt.Println("  go mod tidy")
+	fmt.Printf("  %s\n", cfg.runHint())
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+)
+
+// The markers around the hand-written part of a regenerated file. Lines
+// between them are carried over verbatim by hexagen regen.
+const (
+	customBegin = "// hexagen:custom:begin"
+	customEnd   = "// hexagen:custom:end"
+)
+
+// filterType matches the filter type a repository declares for its model.
+var filterType = regexp.MustCompile(`(?m)^type (\w+)Filter struct`)
+
+func runRegen(args []string) {
+	fs := flag.NewFlagSet("regen", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: hexagen regen router [flags] <service>")
+		fmt.Fprintln(fs.Output(), "Rewrites services/<service>/routes/router.go from the template, keeping the lines between")
+		fmt.Fprintln(fs.Output(), customBegin+" and "+customEnd+".")
+		fs.PrintDefaults()
+	}
+	root := fs.String("r", ".", "Project directory")
+	specPath := fs.String("spec", "", "Spec the project was applied from, for its resources and features")
+	force := fs.Bool("force", false, "Rewrite a router without custom markers, dropping its hand-written routes")
+	disableEmoji := disableEmojiFlag(fs)
+	if len(args) == 0 || args[0] != "router" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	fs.Parse(args[1:])
+	setupStatus(*disableEmoji)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	path, err := regenRouter(*root, fs.Arg(0), *specPath, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s Regenerated %s\n", markOK, path)
+}
+
+// regenRouter renders the router of service in the project at root again
+// and splices the custom section of the current file into it. It returns
+// the path written.
+func regenRouter(root, service, specPath string, force bool) (string, error) {
+	module, problems := readGoMod(filepath.Join(root, "go.mod"))
+	if module == "" {
+		return "", fmt.Errorf("%s: %v", filepath.Join(root, "go.mod"), problems)
+	}
+
+	cfg, _, err := detectConfig(root, module)
+	if err != nil {
+		return "", err
+	}
+	if specPath != "" {
+		spec, err := loadSpec(specPath)
+		if err != nil {
+			return "", err
+		}
+		if err := spec.validate(); err != nil {
+			return "", err
+		}
+		if spec.Module != module {
+			return "", fmt.Errorf("%s is for module %s, but %s declares %s", specPath, spec.Module, filepath.Join(root, "go.mod"), module)
+		}
+		cfg = spec.config()
+	}
+	if !slices.Contains(cfg.Services, service) {
+		return "", fmt.Errorf("no service %q under %s/", service, cfg.layoutPath("services"))
+	}
+
+	dir := "services/" + service + "/"
+	if specPath == "" {
+		// Without a spec every service has the default resource; refuse
+		// to replace the routes of another one.
+		repo, err := os.ReadFile(filepath.Join(root, cfg.layoutPath(dir+"data/repository.go")))
+		want := cfg.resource(service).data().Model
+		if m := filterType.FindSubmatch(repo); err == nil && m != nil && string(m[1]) != want {
+			return "", fmt.Errorf("service %s serves %s, not the default %s; pass -spec with the spec it was applied from", service, m[1], want)
+		}
+	}
+
+	var router templateFile
+	for _, f := range cfg.serviceFiles(service) {
+		if f.Output == dir+"routes/router.go" {
+			router = f
+		}
+	}
+	path := filepath.Join(root, cfg.layoutPath(router.Output))
+	current, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	custom, found, err := customSection(current)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if !found && !force {
+		return "", fmt.Errorf("%s has no %s marker; pass -force to replace it, hand-written routes and all", path, customBegin)
+	}
+
+	data := cfg.templateData()
+	data.Service = cfg.serviceData(service)
+	out, err := renderBytes(router.Output, router.Template, cfg, data)
+	if err != nil {
+		return "", err
+	}
+	if found {
+		out, err = spliceCustom(out, custom)
+		if err != nil {
+			return "", err
+		}
+	}
+	// The license options aren't recorded in the project; keep the header
+	// the file was generated with, year included.
+	if header := licenseComment(current); header != nil {
+		out = append(header, out...)
+	}
+	return path, writeFile(path, out)
+}
+
+// licenseComment returns the comment block -license-header put at the top
+// of src, with the blank line after it, or nil.
+func licenseComment(src []byte) []byte {
+	end := bytes.Index(src, []byte("\n\n"))
+	if end < 0 || !bytes.HasPrefix(src, []byte("// Copyright ")) {
+		return nil
+	}
+	block := src[:end+2]
+	if !bytes.Contains(block, []byte("\n// SPDX-License-Identifier: ")) {
+		return nil
+	}
+	for _, line := range bytes.Split(bytes.TrimSuffix(block, []byte("\n\n")), []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("//")) {
+			return nil
+		}
+	}
+	return block
+}
+
+// customSection returns the lines between the custom markers of src,
+// markers excluded. found is false when src has no begin marker.
+func customSection(src []byte) (custom []byte, found bool, err error) {
+	begin := bytes.Index(src, []byte(customBegin))
+	if begin < 0 {
+		return nil, false, nil
+	}
+	rest := src[begin+len(customBegin):]
+	end := bytes.Index(rest, []byte(customEnd))
+	if end < 0 {
+		return nil, true, fmt.Errorf("%s without %s", customBegin, customEnd)
+	}
+	// Keep whole lines: from the one after the begin marker to the one
+	// holding the end marker, exclusive.
+	body := rest[:end]
+	if i := bytes.IndexByte(body, '\n'); i >= 0 {
+		body = body[i+1:]
+	} else {
+		body = nil
+	}
+	if i := bytes.LastIndexByte(body, '\n'); i >= 0 {
+		body = body[:i+1]
+	} else {
+		body = nil
+	}
+	return body, true, nil
+}
+
+// spliceCustom puts custom between the markers of the freshly rendered src
+// and formats the result, so a section written for the old file keeps
+// compiling in the new one's indentation.
+func spliceCustom(src, custom []byte) ([]byte, error) {
+	begin := bytes.Index(src, []byte(customBegin))
+	end := bytes.Index(src, []byte(customEnd))
+	if begin < 0 || end < begin {
+		return nil, fmt.Errorf("template has no %s section", customBegin)
+	}
+	start := begin + bytes.IndexByte(src[begin:], '\n') + 1
+	stop := bytes.LastIndexByte(src[:end], '\n') + 1
+
+	var out bytes.Buffer
+	out.Write(src[:start])
+	out.Write(custom)
+	out.Write(src[stop:])
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("custom section doesn't parse in the regenerated router: %w", err)
+	}
+	return formatted, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCustomSection(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		want      string
+		wantFound bool
+		wantErr   bool
+	}{
+		{name: "no markers", src: "package routes\n\nfunc f() {}\n"},
+		{name: "begin without end", src: "func f() {\n\t" + customBegin + "\n\tg()\n}\n", wantFound: true, wantErr: true},
+		{name: "end only", src: "func f() {\n\t" + customEnd + "\n}\n"},
+		{name: "empty section", src: "func f() {\n\t" + customBegin + "\n\t" + customEnd + "\n}\n", wantFound: true},
+		{
+			name:      "lines kept verbatim",
+			src:       "func f() {\n\t" + customBegin + "\n\tg()\n\n  // note\n        h()\n\t" + customEnd + "\n}\n",
+			want:      "\tg()\n\n  // note\n        h()\n",
+			wantFound: true,
+		},
+		{
+			name:      "unindented markers",
+			src:       "func f() {\n" + customBegin + " keep\ng()\n" + customEnd + "\n}\n",
+			want:      "g()\n",
+			wantFound: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := customSection([]byte(tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("customSection() error = %v, want error %v", err, tt.wantErr)
+			}
+			if found != tt.wantFound {
+				t.Errorf("customSection() found = %v, want %v", found, tt.wantFound)
+			}
+			if string(got) != tt.want {
+				t.Errorf("customSection() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSpliceCustom(t *testing.T) {
+	const rendered = "package routes\n\nfunc register() {\n\tbase()\n\t" + customBegin + "\n\t" + customEnd + "\n}\n"
+	tests := []struct {
+		name    string
+		src     string
+		custom  string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "empty section",
+			src:  rendered,
+			want: rendered,
+		},
+		{
+			name:   "tab indentation",
+			src:    rendered,
+			custom: "\textra()\n",
+			want:   "package routes\n\nfunc register() {\n\tbase()\n\t" + customBegin + "\n\textra()\n\t" + customEnd + "\n}\n",
+		},
+		{
+			name:   "space indentation",
+			src:    rendered,
+			custom: "    extra()\n        if true {\n  more()\n }\n",
+			want:   "package routes\n\nfunc register() {\n\tbase()\n\t" + customBegin + "\n\textra()\n\tif true {\n\t\tmore()\n\t}\n\t" + customEnd + "\n}\n",
+		},
+		{
+			name:    "template without markers",
+			src:     "package routes\n\nfunc register() {}\n",
+			custom:  "\textra()\n",
+			wantErr: true,
+		},
+		{
+			name:    "custom section that doesn't parse",
+			src:     rendered,
+			custom:  "\textra(\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := spliceCustom([]byte(tt.src), []byte(tt.custom))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("spliceCustom() = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("spliceCustom(): %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("spliceCustom() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegenRouterIsStable(t *testing.T) {
+	root := t.TempDir()
+	if err := generate(testConfig(root)); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(root, "services", "orders", "routes", "router.go")
+	src, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const route = "g.GET(\"/orders/export\", func(c *gin.Context) { c.Status(http.StatusNoContent) })"
+	src = bytes.Replace(src, []byte(customBegin+"\n"), []byte(customBegin+"\n    "+route+"\n"), 1)
+	if err := os.WriteFile(path, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var runs [][]byte
+	for range 2 {
+		if _, err := regenRouter(root, "orders", "", false); err != nil {
+			t.Fatalf("regenRouter: %v", err)
+		}
+		out, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		runs = append(runs, out)
+	}
+	if !strings.Contains(string(runs[0]), "\t"+route+"\n") {
+		t.Errorf("regenerated router lost the custom route:\n%s", runs[0])
+	}
+	if !bytes.Equal(runs[0], runs[1]) {
+		t.Errorf("second regen changed the router:\n%s\nthen:\n%s", runs[0], runs[1])
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Replace is a go.mod replace directive, given to -replace as
+// old[@version]=new[@version].
+type Replace struct {
+	Old        string
+	OldVersion string
+	// New is a module path, which needs NewVersion, or a local directory
+	// relative to the project directory, which can't have one.
+	New        string
+	NewVersion string
+}
+
+// moduleVersionPattern matches a semantic module version such as v1.2.3 or
+// v0.0.0-20240101000000-abcdef123456.
+var moduleVersionPattern = regexp.MustCompile(`^v[0-9]+\.[0-9]+\.[0-9]+(-[0-9A-Za-z.-]+)?(\+incompatible)?$`)
+
+// parseReplaces splits a comma-separated -replace list and validates each
+// directive.
+func parseReplaces(list string) ([]Replace, error) {
+	var replaces []Replace
+	seen := map[string]bool{}
+	for _, s := range strings.Split(list, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		r, err := parseReplace(s)
+		if err != nil {
+			return nil, err
+		}
+		key := r.Old + "@" + r.OldVersion
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate replace of %s", r.oldString())
+		}
+		seen[key] = true
+		replaces = append(replaces, r)
+	}
+	return replaces, nil
+}
+
+func parseReplace(s string) (Replace, error) {
+	from, to, ok := strings.Cut(s, "=")
+	if !ok || from == "" || to == "" {
+		return Replace{}, fmt.Errorf("invalid replace %q: want old=new, e.g. github.com/me/lib=../lib", s)
+	}
+	var r Replace
+	r.Old, r.OldVersion, _ = strings.Cut(from, "@")
+	if !modulePattern.MatchString(r.Old) {
+		return Replace{}, fmt.Errorf("invalid replace %q: %q isn't a module path", s, r.Old)
+	}
+	if strings.Contains(from, "@") && !moduleVersionPattern.MatchString(r.OldVersion) {
+		return Replace{}, fmt.Errorf("invalid replace %q: %q isn't a module version such as v1.2.3", s, r.OldVersion)
+	}
+
+	if isLocalPath(to) {
+		if strings.ContainsAny(to, "\n\r") {
+			return Replace{}, fmt.Errorf("invalid replace %q: the directory can't contain line breaks", s)
+		}
+		r.New = to
+		return r, nil
+	}
+	var versioned bool
+	r.New, r.NewVersion, versioned = strings.Cut(to, "@")
+	if !versioned || !modulePattern.MatchString(r.New) {
+		return Replace{}, fmt.Errorf("invalid replace %q: want a directory starting with ./, ../ or / or a module@version, got %q", s, to)
+	}
+	if !moduleVersionPattern.MatchString(r.NewVersion) {
+		return Replace{}, fmt.Errorf("invalid replace %q: %q isn't a module version such as v1.2.3", s, r.NewVersion)
+	}
+	return r, nil
+}
+
+// isLocalPath reports whether go would read p as a directory, not a module
+// path, on the right of a replace directive.
+func isLocalPath(p string) bool {
+	return p == "." || p == ".." || strings.HasPrefix(p, "./") || strings.HasPrefix(p, "../") || filepath.IsAbs(p)
+}
+
+func (r Replace) oldString() string {
+	if r.OldVersion == "" {
+		return r.Old
+	}
+	return r.Old + " " + r.OldVersion
+}
+
+// String is the directive as go.mod writes it, without the replace keyword.
+func (r Replace) String() string {
+	if r.NewVersion != "" {
+		return r.oldString() + " => " + r.New + " " + r.NewVersion
+	}
+	dir := r.New
+	if strings.ContainsAny(dir, " \t\"'`") {
+		dir = strconv.Quote(dir)
+	}
+	return r.oldString() + " => " + dir
+}
+
+// replacesUnder rewrites the relative directories of replaces for a go.mod
+// in sub, a slash-separated directory of the project, e.g. services/orders:
+// go resolves them from the go.mod, while -replace takes them from the
+// project directory.
+func replacesUnder(replaces []Replace, sub string) []Replace {
+	up := strings.Repeat("../", strings.Count(path.Clean(sub), "/")+1)
+	var out []Replace
+	for _, r := range replaces {
+		if r.NewVersion == "" && !filepath.IsAbs(r.New) {
+			r.New = path.Join(up, r.New)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// warnMissingReplaceDirs warns about replacement directories without a
+// go.mod, which go mod tidy would fail on. They may be created later, so
+// they aren't an error.
+func warnMissingReplaceDirs(root string, replaces []Replace) {
+	for _, r := range replaces {
+		if r.NewVersion != "" {
+			continue
+		}
+		dir := r.New
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		if !isFile(filepath.Join(dir, "go.mod")) {
+			fmt.Fprintf(os.Stderr, "%s Warning: replace %s: %s has no go.mod; go mod tidy fails until it does\n", markWarn, r.Old, dir)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Resource is the entity a service manages: its model, the fields stored in
+// the repository and the HTTP endpoints exposed for it.
+type Resource struct {
+	// Name is the singular, lower-case resource name, e.g. "order".
+	Name string `yaml:"resource"`
+	// Plural is the route segment, e.g. "orders". Defaults to Name + "s".
+	Plural    string   `yaml:"plural"`
+	Endpoints []string `yaml:"endpoints"`
+	Fields    []Field  `yaml:"fields"`
+}
+
+// Field is a model field besides the generated ID.
+type Field struct {
+	Name     string `yaml:"name"`
+	Type     string `yaml:"type"`
+	Required bool   `yaml:"required"`
+}
+
+var (
+	endpointKinds = []string{"list", "get", "create", "update", "delete"}
+	fieldTypes    = []string{"string", "int", "int64", "float64", "bool"}
+
+	resourceNamePattern = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+	fieldNamePattern    = regexp.MustCompile(`^[a-z][a-zA-Z0-9_]*$`)
+)
+
+// defaultResource is generated for services without a spec.
+var defaultResource = Resource{
+	Name:      "item",
+	Plural:    "items",
+	Endpoints: []string{"list", "get", "create"},
+	Fields:    []Field{{Name: "name", Type: "string", Required: true}},
+}
+
+// withDefaults fills the parts of r a spec may leave out.
+func (r Resource) withDefaults() Resource {
+	if r.Name == "" {
+		r.Name = defaultResource.Name
+	}
+	if r.Plural == "" {
+		r.Plural = r.Name + "s"
+	}
+	if len(r.Endpoints) == 0 {
+		r.Endpoints = defaultResource.Endpoints
+	}
+	if len(r.Fields) == 0 {
+		r.Fields = defaultResource.Fields
+	}
+	return r
+}
+
+// validate returns every problem with r, prefixed with where it was found.
+func (r Resource) validate(where string) []string {
+	var problems []string
+	if !resourceNamePattern.MatchString(r.Name) {
+		problems = append(problems, fmt.Sprintf("%s: invalid resource %q (lower-case letters and digits)", where, r.Name))
+	}
+	if !resourceNamePattern.MatchString(r.Plural) {
+		problems = append(problems, fmt.Sprintf("%s: invalid plural %q (lower-case letters and digits)", where, r.Plural))
+	}
+	for _, name := range []string{r.Name, r.Plural} {
+		if reservedName(name) {
+			problems = append(problems, fmt.Sprintf("%s: %q can't be used as a resource name in generated code", where, name))
+		}
+	}
+	if r.Plural == r.Name {
+		problems = append(problems, fmt.Sprintf("%s: plural must differ from resource %q", where, r.Name))
+	}
+
+	seen := map[string]bool{}
+	for _, e := range r.Endpoints {
+		switch {
+		case !slices.Contains(endpointKinds, e):
+			problems = append(problems, fmt.Sprintf("%s: unknown endpoint %q (valid: %s)", where, e, strings.Join(endpointKinds, ", ")))
+		case seen[e]:
+			problems = append(problems, fmt.Sprintf("%s: duplicate endpoint %q", where, e))
+		}
+		seen[e] = true
+	}
+
+	goNames := map[string]string{}
+	for i, f := range r.Fields {
+		at := fmt.Sprintf("%s.fields[%d]", where, i)
+		if !fieldNamePattern.MatchString(f.Name) {
+			problems = append(problems, fmt.Sprintf("%s: invalid name %q (start with a lower-case letter; letters, digits, _)", at, f.Name))
+			continue
+		}
+		if fieldGoName(f.Name) == "ID" {
+			problems = append(problems, fmt.Sprintf("%s: %q is reserved for the generated ID", at, f.Name))
+		}
+		if prev, ok := goNames[fieldGoName(f.Name)]; ok {
+			problems = append(problems, fmt.Sprintf("%s: %q clashes with field %q", at, f.Name, prev))
+		}
+		goNames[fieldGoName(f.Name)] = f.Name
+		if !slices.Contains(fieldTypes, f.Type) {
+			problems = append(problems, fmt.Sprintf("%s: unknown type %q (valid: %s)", at, f.Type, strings.Join(fieldTypes, ", ")))
+		}
+		if f.Required && f.Type != "string" {
+			problems = append(problems, fmt.Sprintf("%s: required is only supported on string fields", at))
+		}
+	}
+	return problems
+}
+
+// reservedNames are identifiers the generated code already uses next to the
+// resource's variables.
+var reservedNames = []string{"c", "f", "r", "s", "ok", "id", "in", "ctx", "err", "req", "svc", "b", "key", "out", "json", "cache", "m", "t", "got", "want", "repo", "calls", "stored", "data", "filter", "internal", "routes", "context"}
+
+// reservedName reports whether name, used as a Go variable, would clash with
+// a keyword, a predeclared identifier or the generated code.
+func reservedName(name string) bool {
+	return token.IsKeyword(name) || types.Universe.Lookup(name) != nil || slices.Contains(reservedNames, name)
+}
+
+// fieldGoName turns a spec field name into an exported Go identifier:
+// customer_id becomes CustomerID.
+func fieldGoName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		if strings.EqualFold(part, "id") {
+			b.WriteString("ID")
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// resource returns the resource generated for a service.
+func (c Config) resource(service string) Resource {
+	if r, ok := c.Resources[service]; ok {
+		return r.withDefaults()
+	}
+	return defaultResource
+}
+
+// ResourceData is the template view of a Resource.
+type ResourceData struct {
+	// Model is the Go type, e.g. Order; ModelPlural names the list method.
+	Model       string
+	ModelPlural string
+	// Path is the route segment and Label the lower-case singular used in
+	// messages and variable names.
+	Path   string
+	Label  string
+	Fields []FieldData
+	// HasRequired is set when create and update validate a field.
+	HasRequired bool
+	// Filters are the fields the list route filters on: all but float64
+	// ones, whose exact equality makes a poor filter.
+	Filters []FieldData
+	// Examples are the rows cmd/seed inserts, as Go literals per field.
+	Examples [][]FieldValue
+
+	List, Get, Create, Update, Delete bool
+}
+
+// FieldData is the template view of a Field.
+type FieldData struct {
+	Name     string
+	Type     string
+	JSON     string
+	Required bool
+}
+
+// FieldValue is a Go literal for one field of an example row.
+type FieldValue struct {
+	Name  string
+	Value string
+}
+
+// seedRows is the number of example rows cmd/seed inserts per resource.
+const seedRows = 3
+
+// exampleValue returns a Go literal of f's type for the nth example row.
+func exampleValue(f Field, n int) string {
+	switch f.Type {
+	case "string":
+		return strconv.Quote(fmt.Sprintf("%s %d", strings.ReplaceAll(f.Name, "_", " "), n))
+	case "float64":
+		return fmt.Sprintf("%d.5", n*10)
+	case "bool":
+		return strconv.FormatBool(n%2 == 1)
+	default:
+		return strconv.Itoa(n * 10)
+	}
+}
+
+// UsesNotFound reports whether a route maps data.ErrNotFound to 404.
+func (r ResourceData) UsesNotFound() bool {
+	return r.Get || r.Update || r.Delete
+}
+
+// UsesBody reports whether a route binds a request body.
+func (r ResourceData) UsesBody() bool {
+	return r.Create || r.Update
+}
+
+func (r Resource) data() ResourceData {
+	d := ResourceData{
+		Model:       fieldGoName(r.Name),
+		ModelPlural: fieldGoName(r.Plural),
+		Path:        r.Plural,
+		Label:       r.Name,
+		List:        slices.Contains(r.Endpoints, "list"),
+		Get:         slices.Contains(r.Endpoints, "get"),
+		Create:      slices.Contains(r.Endpoints, "create"),
+		Update:      slices.Contains(r.Endpoints, "update"),
+		Delete:      slices.Contains(r.Endpoints, "delete"),
+	}
+	for _, f := range r.Fields {
+		fd := FieldData{
+			Name:     fieldGoName(f.Name),
+			Type:     f.Type,
+			JSON:     f.Name,
+			Required: f.Required,
+		}
+		d.Fields = append(d.Fields, fd)
+		if f.Type != "float64" {
+			d.Filters = append(d.Filters, fd)
+		}
+		d.HasRequired = d.HasRequired || f.Required
+	}
+	for n := 1; n <= seedRows; n++ {
+		var row []FieldValue
+		for _, f := range r.Fields {
+			row = append(row, FieldValue{Name: fieldGoName(f.Name), Value: exampleValue(f, n)})
+		}
+		d.Examples = append(d.Examples, row)
+	}
+	return d
+}
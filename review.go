@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// planOutput records what generation would write without touching the
+// disk, for the interactive review.
+type planOutput struct {
+	dirs, files []string
+	// clean is set when the target is emptied first, so nothing in it
+	// counts as existing.
+	clean bool
+}
+
+func (p *planOutput) mkdirAll(path string) error {
+	p.dirs = append(p.dirs, path)
+	return nil
+}
+
+func (p *planOutput) writeFile(path string, _ []byte, _ fs.FileMode) error {
+	p.files = append(p.files, path)
+	return nil
+}
+
+func (p *planOutput) exists(path string) bool {
+	return !p.clean && diskOutput{}.exists(path)
+}
+
+func (p *planOutput) empty(path string) bool {
+	prefix := filepath.Clean(path) + string(filepath.Separator)
+	for _, planned := range slices.Concat(p.dirs, p.files) {
+		if strings.HasPrefix(filepath.Clean(planned), prefix) {
+			return false
+		}
+	}
+	return p.clean || !diskOutput{}.exists(path) || diskOutput{}.empty(path)
+}
+
+// plan runs generation against a planOutput and returns the directories and
+// files it would create, relative to the target.
+func plan(cfg Config) (dirs, files []string, err error) {
+	p := &planOutput{clean: cfg.Clean}
+	saved, savedSkip := sink, skipExisting
+	sink, skipExisting = p, cfg.Idempotent
+	defer func() {
+		sink, skipExisting = saved, savedSkip
+		writtenFiles, skippedFiles, addedDirs = nil, nil, nil
+	}()
+
+	run := generate
+	if cfg.Monorepo {
+		run = generateMonorepo
+	}
+	if err := run(cfg); err != nil {
+		return nil, nil, err
+	}
+
+	root, _ := filepath.Abs(cfg.Root)
+	rel := func(paths []string) []string {
+		var out []string
+		for _, path := range paths {
+			if r, err := filepath.Rel(root, path); err == nil && r != "." {
+				out = append(out, filepath.ToSlash(r))
+			}
+		}
+		slices.Sort(out)
+		return slices.Compact(out)
+	}
+	return rel(p.dirs), rel(writtenFiles), nil
+}
+
+// confirmPlan shows what will be generated and asks before anything is
+// written. Only a y answer proceeds.
+func confirmPlan(cfg Config, reader *bufio.Reader) (bool, error) {
+	dirs, files, err := plan(cfg)
+	if err != nil {
+		return false, err
+	}
+
+	target, _ := filepath.Abs(cfg.Root)
+	switch {
+	case cfg.archived():
+		target = cfg.Output + " archive " + cfg.archivePath()
+	case cfg.Clean:
+		target += " (emptied first)"
+	}
+
+	fmt.Println("\nAbout to generate:")
+	fmt.Printf("  Module:    %s\n", cfg.ModuleName)
+	fmt.Printf("  Port:      %s\n", cfg.Port)
+	fmt.Printf("  Services:  %s\n", strings.Join(cfg.Services, ", "))
+	fmt.Printf("  Target:    %s\n", target)
+	fmt.Printf("  Features:  %s\n", strings.Join(cfg.enabledFeatures(), ", "))
+	fmt.Printf("  Directories (%d):\n", len(dirs))
+	for _, d := range dirs {
+		fmt.Printf("    %s/\n", d)
+	}
+	fmt.Printf("  Files (%d):\n", len(files))
+	for _, f := range files {
+		fmt.Printf("    %s\n", f)
+	}
+
+	if cfg.Clean {
+		fmt.Print("\nEverything in the target directory will be removed. Proceed? (y/N): ")
+	} else {
+		fmt.Print("\nProceed? (y/N): ")
+	}
+	input, _ := reader.ReadString('\n')
+	return strings.ToLower(strings.TrimSpace(input)) == "y", nil
+}
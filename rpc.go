@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+)
+
+// rpcKinds are the supported values of -rpc.
+var rpcKinds = []string{"connect"}
+
+// bufVersion is the buf CLI version make buf-generate runs when buf isn't
+// installed.
+const bufVersion = "v1.47.2"
+
+// protoTypes maps field types to their proto3 scalar types. Go's int is
+// 64-bit on every platform the app is built for.
+var protoTypes = map[string]string{
+	"string":  "string",
+	"int":     "int64",
+	"int64":   "int64",
+	"float64": "double",
+	"bool":    "bool",
+}
+
+// ProtoData describes the proto file of one service.
+type ProtoData struct {
+	// Package is the proto package, e.g. orders.v1, and GoPackage the
+	// go_package buf generates the stubs into.
+	Package   string
+	GoPackage string
+	// File is the proto file's path relative to the project root.
+	File string
+	// Service is the fully-qualified proto service, e.g.
+	// orders.v1.OrderService.
+	Service string
+	// Fields are the resource message's fields after id and Filters the
+	// list request's, in spec order. UpdatedAt numbers the timestamp added
+	// with -clock, 0 without it.
+	Fields    []ProtoField
+	Filters   []ProtoField
+	UpdatedAt int
+}
+
+// ProtoField is one field of a proto message. Its json_name is its name, so
+// the messages read and write the same JSON as the HTTP API.
+type ProtoField struct {
+	Name   string
+	Type   string
+	Number int
+	// GoName and GoType are the field in the handler's request messages;
+	// integers are server.Int64, which reads the strings the proto JSON
+	// mapping writes 64-bit integers as.
+	GoName string
+	GoType string
+	// ModelType is the field's type in the data model.
+	ModelType string
+}
+
+// protoPackageName is the lower-case proto package segment of a service.
+func protoPackageName(service string) string {
+	return strings.ToLower(service)
+}
+
+func (c Config) protoData(service string) ProtoData {
+	pkg := protoPackageName(service)
+	r := c.resource(service).data()
+	d := ProtoData{
+		Package:   pkg + ".v1",
+		GoPackage: c.ModuleName + "/gen/" + pkg + "/v1;" + pkg + "v1",
+		File:      "proto/" + pkg + "/v1/" + pkg + ".proto",
+		Service:   pkg + ".v1." + r.Model + "Service",
+	}
+	for i, f := range r.Fields {
+		d.Fields = append(d.Fields, protoField(f, i+2))
+	}
+	for i, f := range r.Filters {
+		d.Filters = append(d.Filters, protoField(f, i+1))
+	}
+	if c.Clock {
+		d.UpdatedAt = len(r.Fields) + 2
+	}
+	return d
+}
+
+func protoField(f FieldData, number int) ProtoField {
+	goType := f.Type
+	if protoTypes[f.Type] == "int64" {
+		goType = "server.Int64"
+	}
+	return ProtoField{Name: f.JSON, Type: protoTypes[f.Type], Number: number, GoName: f.Name, GoType: goType, ModelType: f.Type}
+}
+
+// Value converts expr, the field in a request message, to the model's type.
+func (f ProtoField) Value(expr string) string {
+	if f.GoType != f.ModelType {
+		return f.ModelType + "(" + expr + ")"
+	}
+	return expr
+}
+
+// Filter converts expr, the optional field in the list request, to the
+// model filter's pointer.
+func (f ProtoField) Filter(expr string) string {
+	if f.GoType != f.ModelType {
+		return "server.IntPtr[" + f.ModelType + "](" + expr + ")"
+	}
+	return expr
+}
+
+// rpcFiles lists the buf config and the server's shared Connect codec; the
+// per-service proto files and handlers are in serviceFiles.
+func (c Config) rpcFiles() []templateFile {
+	if c.RPC == "" {
+		return nil
+	}
+	return []templateFile{
+		{Output: "buf.yaml", Template: "templates/buf.yaml.tmpl"},
+		{Output: "buf.gen.yaml", Template: "templates/bufGen.yaml.tmpl"},
+		{Output: "commons/server/rpc.go", Template: "templates/rpc.go.tmpl"},
+	}
+}
+
+// bufMakefile is the Makefile target generating the stubs from the proto
+// files, through an installed buf or a pinned go run.
+func bufMakefile() string {
+	return `
+BUF ?= $(shell command -v buf 2>/dev/null || echo "go run github.com/bufbuild/buf/cmd/buf@` + bufVersion + `")
+
+# buf-generate writes the Go messages and Connect stubs of proto/ to gen/,
+# for clients; the server's handlers in services/*/rpc don't need them.
+buf-generate:
+	$(BUF) generate
+`
+}
+
+// String is the field's declaration in a proto message.
+func (f ProtoField) String() string {
+	return f.Type + " " + f.Name + " = " + strconv.Itoa(f.Number) + ` [json_name = "` + f.Name + `"]`
+}
@@ -0,0 +1,404 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the declarative description of a project read by `hexagen apply`.
+type Spec struct {
+	Root     string        `yaml:"root"`
+	Module   string        `yaml:"module"`
+	Port     int           `yaml:"port"`
+	Features SpecFeatures  `yaml:"features"`
+	Services []ServiceSpec `yaml:"services"`
+}
+
+// SpecFeatures mirrors the feature flags.
+type SpecFeatures struct {
+	Framework        string   `yaml:"framework"`
+	Logger           string   `yaml:"logger"`
+	JSONLib          string   `yaml:"json_lib"`
+	DB               string   `yaml:"db"`
+	Cache            string   `yaml:"cache"`
+	RPC              string   `yaml:"rpc"`
+	Envs             []string `yaml:"envs"`
+	Dotenv           bool     `yaml:"dotenv"`
+	Reload           bool     `yaml:"reload"`
+	Clients          []Client `yaml:"clients"`
+	Internal         bool     `yaml:"internal"`
+	BasePath         string   `yaml:"base_path"`
+	Worker           bool     `yaml:"worker"`
+	Docker           bool     `yaml:"docker"`
+	Helm             bool     `yaml:"helm"`
+	IngressHost      string   `yaml:"ingress_host"`
+	Registry         string   `yaml:"registry"`
+	APIVersion       string   `yaml:"api_version"`
+	Procfile         bool     `yaml:"procfile"`
+	Changelog        bool     `yaml:"changelog"`
+	ToolVersions     bool     `yaml:"tool_versions"`
+	BuildInfo        bool     `yaml:"buildinfo"`
+	RateLimit        bool     `yaml:"ratelimit"`
+	Gzip             bool     `yaml:"gzip"`
+	CORS             bool     `yaml:"cors"`
+	WebSocket        bool     `yaml:"websocket"`
+	OpenAPI          bool     `yaml:"openapi"`
+	TimeoutPerRoute  bool     `yaml:"timeout_per_route"`
+	Metrics          bool     `yaml:"metrics"`
+	Audit            bool     `yaml:"audit"`
+	Clock            bool     `yaml:"clock"`
+	Cursor           bool     `yaml:"cursor"`
+	FeatureFlags     bool     `yaml:"feature_flags"`
+	SeedData         bool     `yaml:"seed_data"`
+	Mocks            bool     `yaml:"mocks"`
+	Healthcheck      bool     `yaml:"healthcheck"`
+	Static           string   `yaml:"static"`
+	StaticEmbed      bool     `yaml:"static_embed"`
+	StaticPath       string   `yaml:"static_path"`
+	License          string   `yaml:"license"`
+	Author           string   `yaml:"author"`
+	AuthorEmail      string   `yaml:"author_email"`
+	LicenseHeader    bool     `yaml:"license_header"`
+	TraceIDHeader    string   `yaml:"trace_id_header"`
+	PerServiceMain   bool     `yaml:"per_service_main"`
+	Monorepo         bool     `yaml:"monorepo"`
+	Gitkeep          bool     `yaml:"gitkeep"`
+	GitkeepEmptyOnly bool     `yaml:"no_gitkeep_on_populated"`
+	Vendor           bool     `yaml:"vendor"`
+	DepsMode         string   `yaml:"deps_mode"`
+	Toolchain        string   `yaml:"toolchain"`
+	Replace          []string `yaml:"replace"`
+	PortFromEnvOnly  bool     `yaml:"port_from_env_only"`
+	SkipMakefile     bool     `yaml:"skip_makefile"`
+}
+
+// ServiceSpec describes one service and the resource it manages.
+type ServiceSpec struct {
+	Name     string `yaml:"name"`
+	Resource `yaml:",inline"`
+}
+
+// modulePattern loosely matches a module path: slash-separated elements of
+// letters, digits and ._~-.
+var modulePattern = regexp.MustCompile(`^[A-Za-z0-9._~-]+(/[A-Za-z0-9._~-]+)*$`)
+
+// loadSpec reads and decodes a spec file. Unknown keys are rejected so typos
+// don't silently drop settings.
+func loadSpec(path string) (Spec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Spec{}, err
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+
+	var spec Spec
+	if err := dec.Decode(&spec); err != nil {
+		return Spec{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// validate checks the whole spec and reports every problem at once.
+func (s Spec) validate() error {
+	var problems []string
+	if s.Module == "" {
+		problems = append(problems, "module: required")
+	} else if !modulePattern.MatchString(s.Module) {
+		problems = append(problems, fmt.Sprintf("module: invalid module path %q", s.Module))
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		problems = append(problems, fmt.Sprintf("port: %d is out of range", s.Port))
+	}
+	if _, ok := lookupFramework(s.Features.Framework); s.Features.Framework != "" && !ok {
+		problems = append(problems, fmt.Sprintf("features.framework: unknown framework %q (valid: %s)%s", s.Features.Framework, strings.Join(frameworkNames(), ", "), suggestion(s.Features.Framework, frameworkNames())))
+	}
+	if s.Features.Logger != "" && !slices.Contains(loggers, s.Features.Logger) {
+		problems = append(problems, fmt.Sprintf("features.logger: unknown logger %q (valid: %s)%s", s.Features.Logger, strings.Join(loggers, ", "), suggestion(s.Features.Logger, loggers)))
+	}
+	if _, ok := jsonLibs[s.Features.JSONLib]; s.Features.JSONLib != "" && !ok {
+		problems = append(problems, fmt.Sprintf("features.json_lib: unknown JSON library %q (valid: %s)%s", s.Features.JSONLib, strings.Join(jsonLibNames(), ", "), suggestion(s.Features.JSONLib, jsonLibNames())))
+	}
+	if _, ok := dbDrivers[s.Features.DB]; s.Features.DB != "" && !ok {
+		problems = append(problems, fmt.Sprintf("features.db: unknown database %q (valid: %s)%s", s.Features.DB, strings.Join(dbNames(), ", "), suggestion(s.Features.DB, dbNames())))
+	}
+	if s.Features.Cache != "" && !slices.Contains(cacheBackends, s.Features.Cache) {
+		problems = append(problems, fmt.Sprintf("features.cache: unknown cache %q (valid: %s)%s", s.Features.Cache, strings.Join(cacheBackends, ", "), suggestion(s.Features.Cache, cacheBackends)))
+	}
+	if s.Features.RPC != "" && !slices.Contains(rpcKinds, s.Features.RPC) {
+		problems = append(problems, fmt.Sprintf("features.rpc: unknown RPC framework %q (valid: %s)%s", s.Features.RPC, strings.Join(rpcKinds, ", "), suggestion(s.Features.RPC, rpcKinds)))
+	}
+	if s.Features.DepsMode != "" && !slices.Contains(depsModes, s.Features.DepsMode) {
+		problems = append(problems, fmt.Sprintf("features.deps_mode: unknown deps mode %q (valid: %s)%s", s.Features.DepsMode, strings.Join(depsModes, ", "), suggestion(s.Features.DepsMode, depsModes)))
+	}
+	if s.Features.Toolchain != "" {
+		if err := validateToolchain(s.Features.Toolchain); err != nil {
+			problems = append(problems, "features.toolchain: "+err.Error())
+		}
+	}
+	if s.Features.APIVersion != "" && !apiVersionPattern.MatchString(s.Features.APIVersion) {
+		problems = append(problems, fmt.Sprintf("features.api_version: invalid API version %q (want v1, v2, ...)", s.Features.APIVersion))
+	}
+	if err := validateIngressHost(Config{Helm: s.Features.Helm, IngressHost: s.Features.IngressHost}); err != nil {
+		problems = append(problems, "features.ingress_host: "+err.Error())
+	}
+	if err := validateTraceIDHeader(Config{Logger: s.Features.Logger, TraceIDHeader: s.Features.TraceIDHeader}); err != nil {
+		problems = append(problems, "features.trace_id_header: "+err.Error())
+	}
+	if err := validateBasePath(Config{BasePath: s.Features.BasePath}); err != nil {
+		problems = append(problems, "features.base_path: "+err.Error())
+	}
+	if err := validateLicense(Config{License: s.Features.License, Author: s.Features.Author, AuthorEmail: s.Features.AuthorEmail, LicenseHeader: s.Features.LicenseHeader}); err != nil {
+		problems = append(problems, "features.license: "+err.Error())
+	}
+	if err := validateRegistry(Config{Docker: s.Features.Docker, Helm: s.Features.Helm, Registry: s.Features.Registry}); err != nil {
+		problems = append(problems, "features.registry: "+err.Error())
+	}
+	if err := validateSkipMakefile(Config{SkipMakefile: s.Features.SkipMakefile, PortFromEnvOnly: s.Features.PortFromEnvOnly}); err != nil {
+		problems = append(problems, "features.skip_makefile: "+err.Error())
+	}
+	if err := validateGitkeep(Config{Gitkeep: s.Features.Gitkeep, GitkeepEmptyOnly: s.Features.GitkeepEmptyOnly}); err != nil {
+		problems = append(problems, "features.no_gitkeep_on_populated: "+err.Error())
+	}
+	if err := validateClients(s.Features.Clients); err != nil {
+		problems = append(problems, "features.clients: "+err.Error())
+	}
+	if _, err := parseReplaces(strings.Join(s.Features.Replace, ",")); err != nil {
+		problems = append(problems, "features.replace: "+err.Error())
+	}
+	for i, name := range s.Features.Envs {
+		if !envNamePattern.MatchString(name) {
+			problems = append(problems, fmt.Sprintf("features.envs[%d]: invalid environment %q", i, name))
+		}
+	}
+
+	if len(s.Services) == 0 {
+		problems = append(problems, "services: at least one service is required")
+	}
+	seen := map[string]bool{}
+	for i, svc := range s.Services {
+		where := fmt.Sprintf("services[%d]", i)
+		switch {
+		case !serviceNamePattern.MatchString(svc.Name):
+			problems = append(problems, fmt.Sprintf("%s: invalid name %q (letters and digits, starting with a letter)", where, svc.Name))
+		case seen[svc.Name]:
+			problems = append(problems, fmt.Sprintf("%s: duplicate service %q", where, svc.Name))
+		}
+		seen[svc.Name] = true
+		problems = append(problems, svc.Resource.withDefaults().validate(where)...)
+		if s.Features.Clock {
+			for j, f := range svc.Fields {
+				if fieldGoName(f.Name) == "UpdatedAt" {
+					problems = append(problems, fmt.Sprintf("%s.fields[%d]: %q is reserved for the clock's timestamp", where, j, f.Name))
+				}
+			}
+		}
+	}
+
+	if err := validateServiceMains(s.config()); err != nil {
+		problems = append(problems, "services: "+err.Error())
+	}
+	if err := validateStatic(s.config()); err != nil {
+		problems = append(problems, "features.static: "+err.Error())
+	}
+	if err := validateReload(s.config()); err != nil {
+		problems = append(problems, "features.reload: "+err.Error())
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid spec:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// config turns a validated spec into the generation config.
+func (s Spec) config() Config {
+	cfg := Config{
+		Root:             s.Root,
+		ModuleName:       s.Module,
+		Port:             "8080",
+		Framework:        "gin",
+		Logger:           "zap",
+		JSONLib:          "std",
+		Internal:         s.Features.Internal,
+		BasePath:         s.Features.BasePath,
+		Worker:           s.Features.Worker,
+		Docker:           s.Features.Docker,
+		Helm:             s.Features.Helm,
+		IngressHost:      s.Features.IngressHost,
+		Registry:         s.Features.Registry,
+		APIVersion:       "v1",
+		Procfile:         s.Features.Procfile,
+		Changelog:        s.Features.Changelog,
+		ToolVersions:     s.Features.ToolVersions,
+		BuildInfo:        s.Features.BuildInfo,
+		RateLimit:        s.Features.RateLimit,
+		Gzip:             s.Features.Gzip,
+		CORS:             s.Features.CORS,
+		WebSocket:        s.Features.WebSocket,
+		OpenAPI:          s.Features.OpenAPI,
+		TimeoutPerRoute:  s.Features.TimeoutPerRoute,
+		Metrics:          s.Features.Metrics,
+		Audit:            s.Features.Audit,
+		Clock:            s.Features.Clock,
+		Cursor:           s.Features.Cursor,
+		FeatureFlags:     s.Features.FeatureFlags,
+		SeedData:         s.Features.SeedData,
+		Mocks:            s.Features.Mocks,
+		Healthcheck:      s.Features.Healthcheck,
+		Static:           s.Features.Static,
+		StaticEmbed:      s.Features.StaticEmbed,
+		StaticPath:       defaultStaticPath,
+		License:          s.Features.License,
+		Author:           s.Features.Author,
+		AuthorEmail:      s.Features.AuthorEmail,
+		LicenseHeader:    s.Features.LicenseHeader,
+		TraceIDHeader:    s.Features.TraceIDHeader,
+		PerServiceMain:   s.Features.PerServiceMain,
+		Monorepo:         s.Features.Monorepo,
+		Gitkeep:          s.Features.Gitkeep,
+		GitkeepEmptyOnly: s.Features.GitkeepEmptyOnly,
+		Vendor:           s.Features.Vendor,
+		DepsMode:         s.Features.DepsMode,
+		Toolchain:        s.Features.Toolchain,
+		DB:               s.Features.DB,
+		Cache:            s.Features.Cache,
+		RPC:              s.Features.RPC,
+		PortFromEnvOnly:  s.Features.PortFromEnvOnly,
+		SkipMakefile:     s.Features.SkipMakefile,
+		Envs:             s.Features.Envs,
+		Dotenv:           s.Features.Dotenv,
+		Reload:           s.Features.Reload,
+		Clients:          s.Features.Clients,
+		Resources:        map[string]Resource{},
+	}
+	// validate has already rejected malformed directives.
+	cfg.Replaces, _ = parseReplaces(strings.Join(s.Features.Replace, ","))
+	if cfg.Root == "" {
+		cfg.Root = "."
+	}
+	if s.Port != 0 {
+		cfg.Port = strconv.Itoa(s.Port)
+	}
+	if s.Features.Framework != "" {
+		cfg.Framework = s.Features.Framework
+	}
+	if s.Features.Logger != "" {
+		cfg.Logger = s.Features.Logger
+	}
+	if s.Features.JSONLib != "" {
+		cfg.JSONLib = s.Features.JSONLib
+	}
+	if s.Features.APIVersion != "" {
+		cfg.APIVersion = s.Features.APIVersion
+	}
+	if s.Features.StaticPath != "" {
+		cfg.StaticPath = s.Features.StaticPath
+	}
+	for _, svc := range s.Services {
+		cfg.Services = append(cfg.Services, svc.Name)
+		cfg.Resources[svc.Name] = svc.Resource
+	}
+	return cfg
+}
+
+// runApply implements `hexagen apply [flags] spec.yaml`.
+func runApply(args []string) {
+	fs := flag.NewFlagSet("apply", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: hexagen apply [flags] spec.yaml")
+		fs.PrintDefaults()
+	}
+	root := fs.String("r", "", "Target directory (overrides root in the spec)")
+	clean := fs.Bool("c", false, "Clean target directory")
+	fs.BoolVar(clean, "clean", false, "Alias for -c")
+	force := fs.Bool("force", false, "Write into a non-empty target directory without removing existing files")
+	idempotent := fs.Bool("idempotent", false, "Only add missing files and directories, leaving existing ones untouched")
+	offline := fs.Bool("offline", false, "Skip all network operations and pin requires in go.mod")
+	depsRetries := fs.Int("deps-retries", 3, "Attempts for go mod tidy before giving up")
+	skipInstall := fs.Bool("skip-install", false, "Don't run go mod tidy after generating; print the commands to run instead")
+	fs.BoolVar(skipInstall, "no-tidy", false, "Alias for -skip-install")
+	strictFlag := fs.Bool("strict", false, "Fail on every error otherwise tolerated: directory creation, .gitkeep writes, -clean removals, dependency installation")
+	sinceGo := fs.String("since-go", "", "Check the spec's features against this Go version instead of the local toolchain's")
+	outputMode := fs.String("output", "dir", "Where to put the project: dir, zip or tgz (an archive at -archive)")
+	archive := fs.String("archive", "", "Archive path for -output zip or tgz, - for stdout (default <name>.zip or <name>.tar.gz)")
+	summaryFile := fs.String("summary-file", "", "Write a JSON report of the generation to this path inside the project")
+	printTreeFlag := fs.Bool("print-tree", false, "Print the generated files as a tree after generation")
+	dumpConfigFlag := fs.Bool("dump-config", false, "Print the options resolved from the spec and flags as JSON and exit without generating")
+	openFlag := fs.Bool("open", false, "Open the project in $EDITOR, or code or goland from PATH, once it is ready (skipped when stdout isn't a terminal)")
+	disableEmoji := disableEmojiFlag(fs)
+	fs.Parse(args)
+	setupStatus(*disableEmoji)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	spec, err := loadSpec(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := spec.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	cfg := spec.config()
+	if *root != "" {
+		cfg.Root = *root
+	}
+	cfg.Clean = *clean
+	cfg.Force = *force
+	cfg.Idempotent = *idempotent
+	cfg.Offline = *offline
+	cfg.DepsRetries = *depsRetries
+	cfg.SkipInstall = *skipInstall
+	cfg.Strict = *strictFlag
+	cfg.SummaryFile = *summaryFile
+	cfg.PrintTree = *printTreeFlag
+	cfg.Open = *openFlag
+	cfg.Output = *outputMode
+	cfg.Archive = *archive
+
+	if cfg.Idempotent && (cfg.Force || cfg.Clean) {
+		fmt.Fprintln(os.Stderr, "Error: -idempotent keeps existing files; it can't be combined with -force or -clean")
+		os.Exit(2)
+	}
+	if err := validateOutput(&cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+
+	if err := adoptExistingModule(&cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if err := verifyGoVersion(cfg, *sinceGo); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	if cfg.ToolVersions {
+		cfg.GoVersion = toolVersion(*sinceGo)
+	}
+
+	if *dumpConfigFlag {
+		if err := dumpConfig(os.Stdout, cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	execute(cfg)
+}
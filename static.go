@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// defaultStaticPath is where -static files are mounted without -static-path.
+const defaultStaticPath = "/static"
+
+// staticPathPattern is a URL path of one or more plain segments, without a
+// trailing slash.
+var staticPathPattern = regexp.MustCompile(`^(/[A-Za-z0-9._~-]+)+$`)
+
+// reservedStaticPaths are the routes generated outside the API that a mount
+// path would shadow.
+var reservedStaticPaths = []string{"/healthz", "/metrics", "/version"}
+
+// validateStatic checks -static, -static-embed and -static-path. The
+// directory must be inside the project and outside the generated Go trees,
+// and an embedded one needs a parent directory that can hold the embedding
+// package.
+func validateStatic(c Config) error {
+	if c.Static == "" {
+		if c.StaticEmbed {
+			return fmt.Errorf("-static-embed needs -static, the directory to embed")
+		}
+		if c.StaticPath != defaultStaticPath {
+			return fmt.Errorf("-static-path needs -static, the directory to serve")
+		}
+		return nil
+	}
+
+	dir := c.staticDir()
+	if filepath.IsAbs(c.Static) || dir == "." || dir == ".." || strings.HasPrefix(dir, "../") {
+		return fmt.Errorf("invalid static directory %q: want a directory inside the project, e.g. ./public", c.Static)
+	}
+	top, _, _ := strings.Cut(dir, "/")
+	base, _, _ := strings.Cut(c.BasePath, "/")
+	if slices.Contains(append(slices.Clone(internalTrees), "cmd", "internal", migrationsDir, base), top) {
+		return fmt.Errorf("invalid static directory %q: %s/ holds generated code; use a directory of its own, e.g. ./public", c.Static, top)
+	}
+	if c.StaticEmbed {
+		base := path.Base(dir)
+		if strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_") || strings.ContainsAny(dir, " \"`") {
+			return fmt.Errorf("invalid static directory %q: go:embed can't name it; avoid spaces, quotes and a leading . or _", c.Static)
+		}
+		if c.staticPackage() == "" {
+			return fmt.Errorf("invalid static directory %q: its parent %s must be a valid package name to embed it", c.Static, path.Dir(dir))
+		}
+	}
+
+	p := c.StaticPath
+	if !staticPathPattern.MatchString(p) {
+		return fmt.Errorf("invalid static path %q: want a URL path such as /static or /assets, without a trailing slash", p)
+	}
+	if p == "/api" || strings.HasPrefix(p, "/api/") || slices.Contains(reservedStaticPaths, p) {
+		return fmt.Errorf("invalid static path %q: it clashes with the generated routes", p)
+	}
+	return nil
+}
+
+// staticDir is -static as a clean, slash-separated path relative to the
+// project.
+func (c Config) staticDir() string {
+	return path.Clean(filepath.ToSlash(c.Static))
+}
+
+// staticEmbedDir is the directory of the package embedding the static
+// files: go:embed can't reach outside its package, so it is the static
+// directory's parent.
+func (c Config) staticEmbedDir() string {
+	return path.Dir(c.staticDir())
+}
+
+// staticPackage names the embedding package after its directory, or after
+// the module at the project root; "" when that isn't a valid name.
+func (c Config) staticPackage() string {
+	name := path.Base(c.staticEmbedDir())
+	if name == "." {
+		name = strings.ToLower(strings.NewReplacer("-", "", ".", "").Replace(path.Base(c.ModuleName)))
+	}
+	if !basePathPattern.MatchString(name) || token.IsKeyword(name) {
+		return ""
+	}
+	return name
+}
+
+func (c Config) staticImport() string {
+	if dir := c.staticEmbedDir(); dir != "." {
+		return c.ModuleName + "/" + dir
+	}
+	return c.ModuleName
+}
+
+// staticFiles lists the handler and, with -static-embed, the embedding
+// package.
+func (c Config) staticFiles() []templateFile {
+	if c.Static == "" {
+		return nil
+	}
+	files := []templateFile{{Output: "commons/server/static.go", Template: "templates/static.go.tmpl"}}
+	if c.StaticEmbed {
+		files = append(files, templateFile{Output: path.Join(c.staticEmbedDir(), "static.go"), Template: "templates/staticEmbed.go.tmpl"})
+	}
+	return files
+}
+
+// writeStaticDir creates the static directory with a placeholder index.html,
+// leaving an index already there alone.
+func writeStaticDir(root string, cfg Config) error {
+	dir := filepath.Join(root, filepath.FromSlash(cfg.staticDir()))
+	if err := makeDir(dir); err != nil {
+		return err
+	}
+	index := filepath.Join(dir, "index.html")
+	if sink.exists(index) {
+		return nil
+	}
+	content := `<!doctype html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>` + cfg.serviceName() + `</title>
+</head>
+<body>
+  <h1>` + cfg.serviceName() + `</h1>
+  <p>Served from ` + cfg.staticDir() + `/ under ` + cfg.StaticPath + `/. Replace this page with your assets.</p>
+</body>
+</html>
+`
+	return writeFile(index, []byte(content))
+}
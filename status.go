@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// The markers starting status messages, and the branches of -print-tree.
+// They are emoji and box drawing by default and plain ASCII after
+// useASCIIStatus.
+var (
+	markOK   = "✓"
+	markFail = "✗"
+	markWarn = "⚠"
+	markWait = "⏳"
+
+	treeBranch, treeLast       = "├── ", "└── "
+	treeIndent, treeLastIndent = "│   ", "    "
+)
+
+// disableEmojiFlag defines -disable-emoji on fs, for every command printing
+// status messages.
+func disableEmojiFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("disable-emoji", false, "Print ASCII markers such as [ok] instead of emoji (the default in CI, non-UTF-8 locales and legacy Windows consoles)")
+}
+
+// setupStatus switches to ASCII markers when -disable-emoji is set or the
+// environment can't be trusted to show emoji.
+func setupStatus(disable bool) {
+	if disable || !emojiSupported() {
+		useASCIIStatus()
+	}
+}
+
+func useASCIIStatus() {
+	markOK, markFail, markWarn, markWait = "[ok]", "[fail]", "[warn]", "[..]"
+	treeBranch, treeLast = "|-- ", "`-- "
+	treeIndent, treeLastIndent = "|   ", "    "
+}
+
+// emojiSupported reports whether output likely ends up somewhere rendering
+// emoji. It doesn't in CI logs, on dumb terminals, under a locale naming
+// another charset (LC_ALL=C included) or in the Windows console outside
+// Windows Terminal. An unset locale says nothing, so emoji stay.
+func emojiSupported() bool {
+	if os.Getenv("CI") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	if runtime.GOOS == "windows" && os.Getenv("WT_SESSION") == "" {
+		return false
+	}
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+		}
+	}
+	return true
+}
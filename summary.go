@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// summary is the generation record written by -summary-file.
+type summary struct {
+	HexagenVersion string    `json:"hexagen_version"`
+	GeneratedAt    time.Time `json:"generated_at"`
+	Options        Config    `json:"options"`
+	// Files are relative to the project root, sorted.
+	Files []string `json:"files"`
+}
+
+// dumpConfig writes cfg as indented JSON, for -dump-config: the options
+// generation would run with once flags, prompts, the spec and the defaults
+// derived from the target directory are all applied.
+func dumpConfig(w io.Writer, cfg Config) error {
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// summaryPath resolves -summary-file: relative paths are inside the project.
+func (c Config) summaryPath() string {
+	if filepath.IsAbs(c.SummaryFile) {
+		return c.SummaryFile
+	}
+	return filepath.Join(c.Root, c.SummaryFile)
+}
+
+// writeSummary writes the generation record. It bypasses writeFile so that
+// it never lists itself.
+func writeSummary(cfg Config) error {
+	root, err := filepath.Abs(cfg.Root)
+	if err != nil {
+		return err
+	}
+	s := summary{
+		HexagenVersion: version,
+		GeneratedAt:    time.Now().UTC().Truncate(time.Second),
+		Options:        cfg,
+		Files:          []string{},
+	}
+	for _, path := range writtenFiles {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+		s.Files = append(s.Files, filepath.ToSlash(rel))
+	}
+	slices.Sort(s.Files)
+	s.Files = slices.Compact(s.Files)
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := cfg.summaryPath()
+	if err := sink.mkdirAll(filepath.Dir(path)); err != nil {
+		return err
+	}
+	return sink.writeFile(path, append(b, '\n'), 0644)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// treeNode is a directory in the -print-tree output, or a file when it has
+// no children map.
+type treeNode struct {
+	children map[string]*treeNode
+}
+
+// printTree writes the files generation created as an ASCII tree, like the
+// tree command, followed by the directory and file counts.
+func printTree(w io.Writer, cfg Config) {
+	root, _ := filepath.Abs(cfg.Root)
+	top := &treeNode{children: map[string]*treeNode{}}
+	for _, path := range writtenFiles {
+		rel, err := filepath.Rel(root, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		n := top
+		for i, part := range parts {
+			child, ok := n.children[part]
+			if !ok {
+				child = &treeNode{}
+				n.children[part] = child
+			}
+			if i < len(parts)-1 && child.children == nil {
+				child.children = map[string]*treeNode{}
+			}
+			n = child
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, cfg.Root)
+	dirs, files := top.print(w, "")
+	fmt.Fprintf(w, "\n%d directories, %d files\n", dirs, files)
+}
+
+// print writes n's children under prefix and counts what it printed.
+func (n *treeNode) print(w io.Writer, prefix string) (dirs, files int) {
+	names := make([]string, 0, len(n.children))
+	for name := range n.children {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for i, name := range names {
+		branch, indent := treeBranch, treeIndent
+		if i == len(names)-1 {
+			branch, indent = treeLast, treeLastIndent
+		}
+		fmt.Fprintln(w, prefix+branch+name)
+
+		child := n.children[name]
+		if child.children == nil {
+			files++
+			continue
+		}
+		d, f := child.print(w, prefix+indent)
+		dirs += d + 1
+		files += f
+	}
+	return dirs, files
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// coreFlags describe the project; outputFlags control how it is written.
+// Everything else is a feature from the registry.
+var (
+	coreFlags   = []string{"m", "module-from-git", "default-module", "p", "license", "author", "author-email", "i", "since-go", "version"}
+	outputFlags = []string{"r", "output", "archive", "c", "clean", "force", "idempotent", "summary-file", "print-tree", "dump-config", "open", "disable-emoji", "deps-retries", "skip-install", "no-tidy", "strict"}
+)
+
+var usageExamples = []string{
+	"hexagen -m github.com/acme/shop -r shop",
+	"hexagen -m github.com/acme/shop -services orders,users -framework stdlib -logger slog",
+	"hexagen -i",
+	"hexagen apply shop.yaml",
+	"hexagen features",
+	"hexagen check ./shop",
+	"hexagen regen router -r shop orders",
+}
+
+// printUsage writes the flags grouped by category, then examples.
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: hexagen [flags]")
+	fmt.Fprintln(w, "       hexagen apply [flags] spec.yaml | features | check [dir] | regen router [flags] <service>")
+
+	printFlagGroup(w, "Core", coreFlags)
+	var featureFlags []string
+	for _, f := range features {
+		featureFlags = append(featureFlags, f.Flag)
+	}
+	printFlagGroup(w, "Features", featureFlags)
+	printFlagGroup(w, "Output", outputFlags)
+
+	fmt.Fprintln(w, "\nExamples:")
+	for _, e := range usageExamples {
+		fmt.Fprintln(w, "  "+e)
+	}
+}
+
+func printFlagGroup(w io.Writer, title string, names []string) {
+	fmt.Fprintf(w, "\n%s:\n", title)
+	for _, name := range names {
+		f := flag.Lookup(name)
+		if f == nil {
+			continue
+		}
+		// Same layout as flag.PrintDefaults.
+		kind, usage := flag.UnquoteUsage(f)
+		line := "  -" + f.Name
+		if kind != "" {
+			line += " " + kind
+		}
+		if len(line) <= 4 {
+			line += "\t"
+		} else {
+			line += "\n    \t"
+		}
+		line += strings.ReplaceAll(usage, "\n", "\n    \t")
+		if v, ok := f.Value.(flag.Getter).Get().(string); ok {
+			if v != "" {
+				line += fmt.Sprintf(" (default %q)", v)
+			}
+		} else if f.DefValue != "false" && f.DefValue != "0" {
+			line += fmt.Sprintf(" (default %v)", f.DefValue)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// parseFlags parses the command line. On a bad flag it prints the error, a
+// targeted hint when there is one and a pointer to -h, and exits with 2.
+func parseFlags() {
+	// The flag package's own error line and full usage are replaced by the
+	// shorter report below.
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	flag.CommandLine.SetOutput(io.Discard)
+	flag.CommandLine.Usage = func() {}
+
+	err := flag.CommandLine.Parse(os.Args[1:])
+	if err == nil {
+		return
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		printUsage(os.Stdout)
+		os.Exit(0)
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if hint := flagHint(err); hint != "" {
+		fmt.Fprintf(os.Stderr, "  hint: %s\n", hint)
+	}
+	fmt.Fprintln(os.Stderr, "Run 'hexagen -h' for usage.")
+	os.Exit(2)
+}
+
+// flagHint turns a flag package error into a suggestion.
+func flagHint(err error) string {
+	msg := err.Error()
+	if name, ok := strings.CutPrefix(msg, "flag provided but not defined: -"); ok {
+		var names []string
+		flag.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+		if s := closest(strings.TrimPrefix(name, "-"), names); s != "" {
+			return fmt.Sprintf("did you mean -%s?", s)
+		}
+		return ""
+	}
+	if name, ok := strings.CutPrefix(msg, "flag needs an argument: -"); ok {
+		if f := flag.Lookup(name); f != nil {
+			return fmt.Sprintf("-%s takes a value: %s", f.Name, f.Usage)
+		}
+		return ""
+	}
+	if m := invalidValue.FindStringSubmatch(msg); m != nil {
+		f := flag.Lookup(m[1])
+		if f == nil {
+			return ""
+		}
+		if bf, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && bf.IsBoolFlag() {
+			return fmt.Sprintf("-%[1]s is a switch: pass -%[1]s alone, or -%[1]s=false", f.Name)
+		}
+		if _, ok := f.Value.(flag.Getter).Get().(int); ok {
+			return fmt.Sprintf("-%[1]s takes a whole number, e.g. -%[1]s=%[2]s", f.Name, f.DefValue)
+		}
+	}
+	return ""
+}
+
+// invalidValue matches the flag package's errors for unparsable values, e.g.
+// `invalid boolean value "yes" for -docker: parse error`.
+var invalidValue = regexp.MustCompile(`^invalid (?:boolean )?value ".*" for (?:flag )?-([^:]+):`)
+
+// closest returns the candidate within two edits of s, or "" when none is
+// close enough to be a likely typo.
+func closest(s string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := editDistance(s, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// suggestion formats closest as a sentence suffix for validation errors.
+func suggestion(s string, candidates []string) string {
+	if c := closest(s, candidates); c != "" {
+		return fmt.Sprintf("; did you mean %q?", c)
+	}
+	return ""
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}